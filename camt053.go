@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/saranrapjs/chase-the-devil/chase"
+)
+
+const (
+	camtDate     = "2006-01-02"
+	camtDateTime = "2006-01-02T15:04:05"
+)
+
+// writeCAMT053 encodes a Statement as an ISO 20022 camt.053 bank to customer statement, for
+// European accounting software. camt amounts are unsigned, with a CdtDbtInd saying which way
+// they move the account from its holder's point of view, following the same sign convention as
+// Values(): charges are debits and payments are credits.
+func writeCAMT053(w io.Writer, s *chase.Statement) error {
+	bw := bufio.NewWriter(w)
+	start, end := statementSpan(s)
+	if !s.PeriodEnd.IsZero() {
+		start, end = s.PeriodStart, s.PeriodEnd
+	}
+	now := time.Now().UTC()
+	if end.IsZero() {
+		start, end = now, now
+	}
+	acctID := strings.Replace(s.AccountNumber, " ", "", -1)
+	if acctID == "" {
+		acctID = "0"
+	}
+	id := acctID + "-" + end.Format("20060102")
+
+	bw.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	bw.WriteString(`<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02">` + "\n")
+	bw.WriteString("<BkToCstmrStmt>\n")
+	fmt.Fprintf(bw, "<GrpHdr>\n<MsgId>%s</MsgId>\n<CreDtTm>%s</CreDtTm>\n</GrpHdr>\n", id, now.Format(camtDateTime))
+	bw.WriteString("<Stmt>\n")
+	fmt.Fprintf(bw, "<Id>%s</Id>\n<CreDtTm>%s</CreDtTm>\n", id, now.Format(camtDateTime))
+	fmt.Fprintf(bw, "<FrToDt>\n<FrDtTm>%s</FrDtTm>\n<ToDtTm>%s</ToDtTm>\n</FrToDt>\n",
+		start.Format(camtDateTime), end.Format(camtDateTime))
+	fmt.Fprintf(bw, "<Acct>\n<Id>\n<Othr>\n<Id>%s</Id>\n</Othr>\n</Id>\n<Ccy>%s</Ccy>\n</Acct>\n", ofxEscape(acctID), s.Currency)
+	// A credit card's balance is owed by its holder, so it's a debit balance, while a deposit
+	// account's is a credit one.
+	holder := chase.Cents(-1)
+	if s.Deposit {
+		holder = 1
+	}
+	writeCAMTBalance(bw, "OPBD", holder*s.StartingBalance, s.Currency, start)
+	writeCAMTBalance(bw, "CLBD", holder*s.EndingBalance, s.Currency, end)
+	for _, t := range s.Transactions {
+		bw.WriteString("<Ntry>\n")
+		fmt.Fprintf(bw, "<Amt Ccy=\"%s\">%s</Amt>\n<CdtDbtInd>%s</CdtDbtInd>\n", s.Currency, t.Amount.Abs(), camtIndicator(-t.Amount))
+		bw.WriteString("<Sts>BOOK</Sts>\n")
+		fmt.Fprintf(bw, "<BookgDt>\n<Dt>%s</Dt>\n</BookgDt>\n", t.Date.Format(camtDate))
+		fmt.Fprintf(bw, "<ValDt>\n<Dt>%s</Dt>\n</ValDt>\n", t.Posted().Format(camtDate))
+		fmt.Fprintf(bw, "<AcctSvcrRef>%s</AcctSvcrRef>\n", t.ID())
+		fmt.Fprintf(bw, "<BkTxCd>\n<Prtry>\n<Cd>%s</Cd>\n</Prtry>\n</BkTxCd>\n", t.Kind)
+		fmt.Fprintf(bw, "<NtryDtls>\n<TxDtls>\n<RmtInf>\n<Ustrd>%s</Ustrd>\n</RmtInf>\n</TxDtls>\n</NtryDtls>\n", ofxEscape(t.Merchant()))
+		bw.WriteString("</Ntry>\n")
+	}
+	bw.WriteString("</Stmt>\n</BkToCstmrStmt>\n</Document>\n")
+	return bw.Flush()
+}
+
+// writeCAMTBalance writes a <Bal> of the given type code, where amt is positive when it's in
+// the account holder's favour.
+func writeCAMTBalance(bw *bufio.Writer, code string, amt chase.Cents, currency string, date time.Time) {
+	fmt.Fprintf(bw, "<Bal>\n<Tp>\n<CdOrPrtry>\n<Cd>%s</Cd>\n</CdOrPrtry>\n</Tp>\n", code)
+	fmt.Fprintf(bw, "<Amt Ccy=\"%s\">%s</Amt>\n<CdtDbtInd>%s</CdtDbtInd>\n", currency, amt.Abs(), camtIndicator(amt))
+	fmt.Fprintf(bw, "<Dt>\n<Dt>%s</Dt>\n</Dt>\n</Bal>\n", date.Format(camtDate))
+}
+
+// camtIndicator is the CdtDbtInd for amt, which is positive for a credit.
+func camtIndicator(amt chase.Cents) string {
+	if amt < 0 {
+		return "DBIT"
+	}
+	return "CRDT"
+}
@@ -0,0 +1,119 @@
+package chase
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Cents is an amount of money in hundredths of its currency, kept as an integer so that summing
+// a statement's transactions is exact. Amounts are only turned into floats or text on their way
+// out, with Float and String.
+type Cents int64
+
+// Float returns c in whole units of its currency, e.g. 12.34 for 1234 cents.
+func (c Cents) Float() float64 {
+	return float64(c) / 100
+}
+
+// String formats c with two decimal places, e.g. "-12.34", the way amounts are printed on
+// statements and in Chase's CSV export.
+func (c Cents) String() string {
+	sign := ""
+	if c < 0 {
+		sign = "-"
+		c = -c
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, c/100, c%100)
+}
+
+// ParseCents parses an amount written the way statements print them, e.g. "$1,234.56" or
+// "-12.34", rounding any fractions of a cent half up.
+func ParseCents(s string) (Cents, error) {
+	return sanitizeAmount(strings.TrimSpace(s), RoundHalfUp)
+}
+
+// Locale is how dates and amounts are written out, which only affects output: statements are
+// always parsed the way Chase prints them.
+type Locale struct {
+	// Date is a time.Format layout.
+	Date string
+
+	// Decimal separates dollars from cents, and Thousands, if set, groups the digits of
+	// the dollars.
+	Decimal   string
+	Thousands string
+}
+
+// US is the locale of Chase's own CSV export, e.g. 01/02/2006 and -1234.56.
+var US = Locale{Date: "01/02/2006", Decimal: "."}
+
+// Amount formats c for the locale.
+func (l Locale) Amount(c Cents) string {
+	sign := ""
+	if c < 0 {
+		sign = "-"
+		c = -c
+	}
+	dollars := strconv.FormatInt(int64(c/100), 10)
+	if l.Thousands != "" {
+		for i := len(dollars) - 3; i > 0; i -= 3 {
+			dollars = dollars[:i] + l.Thousands + dollars[i:]
+		}
+	}
+	return fmt.Sprintf("%s%s%s%02d", sign, dollars, l.Decimal, int64(c%100))
+}
+
+// Rounding is how amounts printed with more than two decimal places are rounded to cents.
+type Rounding int
+
+const (
+	// RoundHalfUp rounds halves away from zero, so 2.675 becomes 2.68 and -2.675 becomes -2.68.
+	RoundHalfUp Rounding = iota
+	// RoundHalfEven rounds halves to the nearest even cent, so 2.675 becomes 2.68 but 2.665
+	// becomes 2.66.
+	RoundHalfEven
+	// RoundTruncate drops any further digits, so 2.679 becomes 2.67.
+	RoundTruncate
+)
+
+// NumberFormat is which characters a statement groups the digits of its amounts with and
+// separates the cents by.
+type NumberFormat int
+
+const (
+	// NumbersUS is Chase's own convention, e.g. 1,234.56.
+	NumbersUS NumberFormat = iota
+	// NumbersEU is the convention of some internationally issued statements, e.g. 1.234,56.
+	NumbersEU
+	// NumbersAuto decides for each amount from where its separators fall: the last of a "." and
+	// "," is the decimal point, as is a lone "," followed by two digits, while a lone "." is
+	// always taken to be one. A lone "," followed by three digits groups them, so "1,234" is
+	// 1234.00 rather than 1.234.
+	NumbersAuto
+)
+
+// usDigits rewrites amt in the US convention, which is what sanitizeAmount parses.
+func (f NumberFormat) usDigits(amt string) string {
+	if f == NumbersAuto {
+		dot, comma := strings.LastIndex(amt, "."), strings.LastIndex(amt, ",")
+		f = NumbersUS
+		if dot >= 0 && comma > dot {
+			f = NumbersEU
+		} else if dot < 0 && comma >= 0 && len(strings.TrimRight(amt[comma+1:], ")-")) == 2 {
+			f = NumbersEU
+		}
+	}
+	if f == NumbersEU {
+		return strings.NewReplacer(".", ",", ",", ".").Replace(amt)
+	}
+	return amt
+}
+
+// Abs returns the magnitude of c.
+func (c Cents) Abs() Cents {
+	if c < 0 {
+		return -c
+	}
+	return c
+}
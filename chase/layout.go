@@ -0,0 +1,62 @@
+package chase
+
+import "regexp"
+
+// Layout is the set of patterns that pick a statement's transactions and balances out of its
+// text, which differ between Chase's account types.
+type Layout struct {
+	// Transaction matches a transaction line, with submatches for its month, day,
+	// description and amount.
+	Transaction *regexp.Regexp
+
+	// PreviousBalance and NewBalance match the opening and closing balances, with the
+	// amount as their only submatch.
+	PreviousBalance *regexp.Regexp
+	NewBalance      *regexp.Regexp
+
+	// Year matches the statement's year, as its only submatch.
+	Year *regexp.Regexp
+
+	// Start and End, if set, mark the beginning and end of the transactions: nothing before
+	// Start's first match or after End's first match following it is parsed as one. A marker
+	// that can't be found leaves that end of the text unbounded.
+	Start *regexp.Regexp
+	End   *regexp.Regexp
+
+	// Header, if set, matches the heading that opens a statement of this layout, so that
+	// SplitAccounts can tell apart the accounts of a combined statement.
+	Header *regexp.Regexp
+
+	// Deposit marks a deposit account's layout, where amounts are printed as they affect the
+	// balance: deposits positive and withdrawals negative. They're negated as they're parsed
+	// so that, as on a credit card, money spent is positive.
+	Deposit bool
+}
+
+// CreditCard is the layout of Chase's credit card statements. Older statements word the balances
+// differently, e.g. "Previous balance: 1,000.00", so they're matched regardless of case, with an
+// optional colon and dollar sign, which a credit balance prints after its minus: "-$12.00".
+var CreditCard = &Layout{
+	Transaction:     regexp.MustCompile(`(?m)^([0-9]{0,2})/([0-9]{0,2}) (.*) (\(?-?\$?[0-9\-\.,]+\)?)`),
+	PreviousBalance: regexp.MustCompile(`(?mi)^Previous Balance:?[ \t]*\$?(-?\$?[0-9\-\.,]+)`),
+	NewBalance:      regexp.MustCompile(`(?mi)^New Balance:?[ \t]*\$?(-?\$?[0-9\-\.,]+)`),
+	Year:            regexp.MustCompile(`(?m)^([0-9]{0,4}) Totals Year-to-Date`),
+	// The account summary above the activity has dates and amounts of its own.
+	Start: regexp.MustCompile(`(?m)^ACCOUNT ACTIVITY`),
+	// Whichever section follows the account activity ends it, so figures in the interest and
+	// rewards sections aren't mistaken for transactions.
+	End:    regexp.MustCompile(`(?m)^(?:[0-9]{4} Totals Year-to-Date|INTEREST CHARGES|.*Amount Rewards)`),
+	Header: regexp.MustCompile(`(?m)^ACCOUNT SUMMARY`),
+}
+
+// Checking is the layout of Chase's checking account statements, whose transaction lines end
+// with the running balance after the amount.
+var Checking = &Layout{
+	Transaction:     regexp.MustCompile(`(?m)^([0-9]{2})/([0-9]{2}) (.*) (-?\$?[0-9,]*\.[0-9]{2}) -?\$?[0-9,]*\.[0-9]{2}$`),
+	PreviousBalance: regexp.MustCompile(`(?mi)^Beginning Balance:?[ \t]*\$?(-?\$?[0-9\-\.,]+)`),
+	NewBalance:      regexp.MustCompile(`(?mi)^Ending Balance:?[ \t]*\$?(-?\$?[0-9\-\.,]+)`),
+	Year:            regexp.MustCompile(`(?m)through [A-Z][a-z]+ [0-9]{1,2}, ([0-9]{4})`),
+	Start:           regexp.MustCompile(`(?m)^TRANSACTION DETAIL`),
+	Header:          regexp.MustCompile(`(?m)^CHECKING SUMMARY`),
+	Deposit:         true,
+}
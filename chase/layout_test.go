@@ -0,0 +1,39 @@
+package chase
+
+import (
+	"regexp"
+	"testing"
+)
+
+// TestBalanceWording checks the balance patterns against the ways statements have worded them.
+func TestBalanceWording(t *testing.T) {
+	tests := []struct {
+		re   *regexp.Regexp
+		line string
+		want Cents
+	}{
+		{CreditCard.PreviousBalance, "Previous Balance $1,000.00", 100000},
+		{CreditCard.PreviousBalance, "Previous balance $1,000.00", 100000},
+		{CreditCard.PreviousBalance, "PREVIOUS BALANCE 1,000.00", 100000},
+		{CreditCard.PreviousBalance, "Previous Balance: $1,000.00", 100000},
+		{CreditCard.PreviousBalance, "Previous balance:1,000.00", 100000},
+		{CreditCard.PreviousBalance, "Previous Balance\t$1,000.00", 100000},
+		{CreditCard.NewBalance, "New Balance $623.45", 62345},
+		{CreditCard.NewBalance, "New balance 623.45", 62345},
+		{CreditCard.NewBalance, "NEW BALANCE: $623.45", 62345},
+		{CreditCard.NewBalance, "New Balance -$12.00", -1200},
+		{Checking.PreviousBalance, "Beginning Balance $1,234.56", 123456},
+		{Checking.PreviousBalance, "Beginning balance: 1,234.56", 123456},
+		{Checking.NewBalance, "Ending Balance $3,130.06", 313006},
+		{Checking.NewBalance, "ending balance -$25.00", -2500},
+	}
+	var p Parser
+	for _, test := range tests {
+		got, err := p.findBalance([]byte("ACCOUNT SUMMARY\n"+test.line+"\n"), test.re, "balance")
+		if err != nil {
+			t.Errorf("%q: %v", test.line, err)
+		} else if got != test.want {
+			t.Errorf("%q: got %s, want %s", test.line, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package chase
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// merchantRules map the noisy names of well-known merchants onto a single clean one. They're
+// tried in order against the whitespace-collapsed merchant text, before any other clean-up.
+var merchantRules = []struct {
+	pattern *regexp.Regexp
+	name    string
+}{
+	{regexp.MustCompile(`(?i)^(AMZN MKTP|AMAZON\.COM|AMAZON MKTPL?A?C?E?)\b`), "Amazon"},
+	{regexp.MustCompile(`(?i)^(AMAZON PRIME|PRIME VIDEO)\b`), "Amazon Prime"},
+	{regexp.MustCompile(`(?i)^UBER\s*\*?\s*EATS\b`), "Uber Eats"},
+	{regexp.MustCompile(`(?i)^UBER\b`), "Uber"},
+	{regexp.MustCompile(`(?i)^LYFT\b`), "Lyft"},
+	{regexp.MustCompile(`(?i)^NETFLIX`), "Netflix"},
+	{regexp.MustCompile(`(?i)^SPOTIFY`), "Spotify"},
+	{regexp.MustCompile(`(?i)^APPLE\.COM/BILL`), "Apple"},
+	{regexp.MustCompile(`(?i)^GOOGLE \*`), "Google"},
+	{regexp.MustCompile(`(?i)^STARBUCKS\b`), "Starbucks"},
+	{regexp.MustCompile(`(?i)^WHOLEFDS|^WHOLE FOODS\b`), "Whole Foods"},
+	{regexp.MustCompile(`(?i)^PAYPAL \*`), "PayPal"},
+}
+
+var (
+	// findProcessorPrefix matches the point-of-sale prefixes (Square, Toast, ...) that some
+	// merchants' names are printed behind.
+	findProcessorPrefix = regexp.MustCompile(`(?i)^(SQ|TST|SP|PY|IC|DD)\s?\*\s*`)
+	// findStoreNumber matches a store number, which is usually followed by nothing but the
+	// store's location.
+	findStoreNumber = regexp.MustCompile(`^(#\S*|[0-9][0-9\-]{2,})$`)
+	findStateCode   = regexp.MustCompile(`^[A-Z]{2}$`)
+	findCityPrefix  = regexp.MustCompile(`(?i)^(SAN|SANTA|LOS|LAS|NEW|FORT|FT|ST|SAINT|EL|PALO|SALT|BATON|GRAND|LONG|JERSEY|KANSAS|OKLAHOMA|CARSON)$`)
+)
+
+// NormalizeMerchant cleans up a raw merchant name as printed on a statement, so transactions
+// from the same merchant can be grouped: well-known merchants get a canonical name, and
+// otherwise point-of-sale prefixes, store numbers and the trailing city/state are dropped and
+// the remainder is title-cased.
+func NormalizeMerchant(name string) string {
+	name = strings.Join(strings.Fields(name), " ")
+	for _, rule := range merchantRules {
+		if rule.pattern.MatchString(name) {
+			return rule.name
+		}
+	}
+	name = findProcessorPrefix.ReplaceAllString(name, "")
+
+	words := strings.Fields(name)
+	for i, w := range words {
+		if i > 0 && findStoreNumber.MatchString(w) {
+			words = words[:i]
+			break
+		}
+	}
+	// Without a store number to cut at, the best that can be done with the location is to
+	// drop the state code and the city before it, which is assumed to be a single word unless
+	// it's something like "SAN FRANCISCO".
+	if n := len(words); n > 3 && findStateCode.MatchString(words[n-1]) {
+		words = words[:n-2]
+		if n := len(words); n > 2 && findCityPrefix.MatchString(words[n-1]) {
+			words = words[:n-1]
+		}
+	}
+
+	for i, w := range words {
+		// The first letter may take more than a byte, as in "ÉPICERIE".
+		r, size := utf8.DecodeRuneInString(w)
+		words[i] = string(unicode.ToTitle(r)) + strings.ToLower(w[size:])
+	}
+	return strings.Join(words, " ")
+}
+
+// Normalize fills in the NormalizedMerchant of each of the statement's transactions.
+func (s *Statement) Normalize() {
+	for i := range s.Transactions {
+		s.Transactions[i].NormalizedMerchant = NormalizeMerchant(s.Transactions[i].MerchantName)
+	}
+}
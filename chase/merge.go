@@ -0,0 +1,90 @@
+package chase
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Dedupe selects how Merge recognises a transaction that appears on more than one statement.
+type Dedupe int
+
+const (
+	// DedupeNone keeps every transaction.
+	DedupeNone Dedupe = iota
+	// DedupeExact treats transactions with the same date, amount and merchant as duplicates.
+	DedupeExact
+	// DedupeFuzzy is like DedupeExact, but ignores case, spacing and punctuation in the merchant.
+	DedupeFuzzy
+)
+
+// Merge combines several statements into one, with all of their transactions sorted together
+// and the balances taken from the earliest and latest statements by closing date. Its interest
+// and fees are the statements' totals, and it reconciles when each of them does.
+//
+// Duplicates are only looked for across statements: a transaction that appears twice on the
+// same statement is kept twice, while one repeated on two statements is kept once. The result
+// doesn't depend on the order the statements are given in.
+func Merge(dedupe Dedupe, statements ...*Statement) *Statement {
+	if len(statements) == 0 {
+		return &Statement{}
+	}
+	sorted := append([]*Statement(nil), statements...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].PeriodEnd.Before(sorted[j].PeriodEnd)
+	})
+	merged := &Statement{
+		StartingBalance: sorted[0].StartingBalance,
+		EndingBalance:   sorted[len(sorted)-1].EndingBalance,
+		AccountNumber:   sorted[0].AccountNumber,
+		Currency:        sorted[0].Currency,
+		Deposit:         sorted[0].Deposit,
+		PeriodStart:     sorted[0].PeriodStart,
+		PeriodEnd:       sorted[len(sorted)-1].PeriodEnd,
+		merged:          true,
+	}
+
+	// kept counts how many of each transaction have been merged so far; a statement only
+	// contributes the copies beyond those already merged from another statement.
+	kept := make(map[string]int)
+	for _, s := range sorted {
+		if s.AccountNumber != merged.AccountNumber {
+			merged.AccountNumber = ""
+		}
+		var tl tally
+		for _, t := range s.Transactions {
+			tl.add(t)
+		}
+		merged.InterestCharged += s.InterestCharged
+		merged.FeesCharged += s.FeesCharged
+		merged.unlisted += s.unlistedCharges(tl)
+		seen := make(map[string]int)
+		for _, t := range s.Transactions {
+			if dedupe == DedupeNone {
+				merged.Transactions = append(merged.Transactions, t)
+				continue
+			}
+			key := dedupeKey(&t, dedupe)
+			seen[key]++
+			if seen[key] > kept[key] {
+				kept[key]++
+				merged.Transactions = append(merged.Transactions, t)
+			}
+		}
+	}
+	sort.Sort(merged.Transactions)
+	return merged
+}
+
+func dedupeKey(t *Transaction, dedupe Dedupe) string {
+	merchant := t.MerchantName
+	if dedupe == DedupeFuzzy {
+		merchant = strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return unicode.ToUpper(r)
+			}
+			return -1
+		}, merchant)
+	}
+	return t.Date.Format("2006-01-02") + "|" + t.Amount.String() + "|" + merchant
+}
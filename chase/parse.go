@@ -0,0 +1,743 @@
+package chase
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+var (
+	findInterestCharged = regexp.MustCompile(`(?m)^Interest Charged \+?\$([0-9\-\.,]+)`)
+	findFeesCharged     = regexp.MustCompile(`(?m)^Fees Charged \+?\$([0-9\-\.,]+)`)
+	findCashAdvances    = regexp.MustCompile(`(?m)^Cash Advances \+?\$([0-9\-\.,]+)`)
+	findPayments        = regexp.MustCompile(`(?m)^Payments?, Credits -?\$([0-9\.,]+)`)
+	findPurchases       = regexp.MustCompile(`(?m)^Purchases \+?\$([0-9\-\.,]+)`)
+	// findSection matches the headings that divide up the account activity.
+	findSection = regexp.MustCompile(`(?m)^(PAYMENTS AND OTHER CREDITS|PURCHASES?|CASH ADVANCES?|BALANCE TRANSFERS?|FEES CHARGED|INTEREST CHARGED)[ \t]*$`)
+	// Some statements call the credit limit the "Credit Access Line", and print it in whole dollars.
+	findCreditLimit     = regexp.MustCompile(`(?m)^(?:Credit Limit|Credit Access Line):? \$?([0-9\.,]+)`)
+	findAvailableCredit = regexp.MustCompile(`(?m)^Available Credit:? \$?([0-9\.,]+)`)
+	findMinimumPayment  = regexp.MustCompile(`(?m)^Minimum Payment Due:? \$([0-9\-\.,]+)`)
+	findPaymentDueDate  = regexp.MustCompile(`(?m)^Payment Due Date:? ([0-9]{2})/([0-9]{2})/([0-9]{2})`)
+	findAccountNumber   = regexp.MustCompile(`(?m)^Account Number: ?([0-9X ]*[0-9X])`)
+	// The rewards summary is worded differently for points and cash back cards, e.g.
+	// "Cash back earned this period +$5.54" or "Points redeemed 5,000", and may be dated, as in
+	// "06/14/24 Points earned this period 1,311".
+	findRewardsEarned   = regexp.MustCompile(`(?mi)^(?:[0-9]{2}/[0-9]{2}(?:/[0-9]{2,4})? )?(?:total )?(?:cash back|points|rewards) earned[^\n0-9$]*\+?\$?([0-9][0-9,]*(?:\.[0-9]+)?)[ \t]*$`)
+	findRewardsRedeemed = regexp.MustCompile(`(?mi)^(?:[0-9]{2}/[0-9]{2}(?:/[0-9]{2,4})? )?(?:total )?(?:cash back|points|rewards) redeemed[^\n0-9$]*-?\$?([0-9][0-9,]*(?:\.[0-9]+)?)[ \t]*$`)
+	// findForeignCurrency matches the lines Chase prints beneath an international purchase,
+	// anchored to the end of the transaction line they follow, e.g.
+	//
+	//	12/19 EURO
+	//	46.10 X 1.176139 (EXCHG RATE)
+	findForeignCurrency = regexp.MustCompile(`^[ \t]*\n[0-9]{2}/[0-9]{2} ([A-Z][A-Z ]*[A-Z])\n([0-9\.,]+) X [0-9\.]+ \(EXCHG RATE\)`)
+	findPostDate        = regexp.MustCompile(`^([0-9]{2})/([0-9]{2}) `)
+	// findReference matches a reference number at either end of a merchant description, long
+	// enough not to be mistaken for a store number.
+	findReference  = regexp.MustCompile(`^([0-9]{12,}) +| +([0-9]{12,})$`)
+	findForeignFee = regexp.MustCompile(`(?i)^FOREIGN TRANSACTION FEE`)
+	findPayment    = regexp.MustCompile(`(?i)PAYMENT.*THANK YOU|AUTOMATIC PAYMENT`)
+	findInterest   = regexp.MustCompile(`(?i)INTEREST CHARGE`)
+	findFee        = regexp.MustCompile(`(?i)\bFEE\b`)
+	findPeriod     = regexp.MustCompile(`(?m)^Opening/Closing Date ([0-9]{2})/([0-9]{2})/([0-9]{2}) - ([0-9]{2})/([0-9]{2})/([0-9]{2})`)
+)
+
+// ReconcileError is returned by ParseStatement when the parsed transactions don't add up to the
+// statement's New Balance.
+type ReconcileError struct {
+	Actual   Cents
+	Expected Cents
+}
+
+func (e *ReconcileError) Error() string {
+	return fmt.Sprintf("reconciliation doesn't match, actual: %s, expected %s", e.Actual, e.Expected)
+}
+
+// DefaultCurrency is the currency statements are assumed to be in.
+const DefaultCurrency = "USD"
+
+// ErrNoTransactions is returned by Parse when not a single transaction line is found, which
+// usually means the statement's layout isn't one the Layout recognizes.
+var ErrNoTransactions = errors.New("no transactions found, the statement layout may be unrecognized")
+
+// Errors collects the problems found while parsing a statement in non-strict mode.
+type Errors []error
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap lets errors.Is and errors.As look inside the collected errors.
+func (e Errors) Unwrap() []error {
+	return e
+}
+
+// Parser holds the options for parsing a statement. The zero value is ready to use.
+type Parser struct {
+	// Strict makes Parse give up on the first malformed transaction or missing balance, rather
+	// than skipping it and carrying on.
+	Strict bool
+
+	// Tolerance is how far the parsed total may drift from the New Balance and
+	// still reconcile. Zero requires an exact match.
+	Tolerance Cents
+
+	// Year, when set, is stamped on every transaction instead of the year found in the statement.
+	Year int
+
+	// FallbackYear is used, with a warning, when the statement's year can't be found and Year
+	// isn't set.
+	FallbackYear int
+
+	// Log, if non-nil, receives warnings about guesses the parser had to make.
+	Log *log.Logger
+
+	// Currency is recorded as the currency of the parsed Statement, defaulting to DefaultCurrency.
+	Currency string
+
+	// Layout is the statement layout to parse, defaulting to CreditCard.
+	Layout *Layout
+
+	// Trace, if non-nil, receives a table of what was captured from each transaction line
+	// and what it was parsed into, for debugging statements that won't reconcile.
+	Trace io.Writer
+
+	// Location is the time zone statement dates are midnight in, defaulting to UTC so that
+	// the same statement gives the same dates wherever it's parsed.
+	Location *time.Location
+
+	// Rounding is how amounts with fractions of a cent are rounded, defaulting to RoundHalfUp.
+	Rounding Rounding
+
+	// KeepRaw keeps the full text each transaction was matched from in its RawLine.
+	KeepRaw bool
+
+	// NumberFormat is how amounts group their digits and mark their cents, defaulting to
+	// NumbersUS.
+	NumberFormat NumberFormat
+
+	// warnings collects the Warnings of ParseWarnings.
+	warnings *[]Warning
+
+	// NewAccount starts the balance from zero when the statement has no Previous Balance, as
+	// on a new card's first statement. Without it, that's only assumed, with a warning, when
+	// the transactions reconcile from zero.
+	NewAccount bool
+}
+
+// ParseStatement parses body with a non-strict Parser that allows DefaultTolerance when reconciling,
+// returning its warnings rather than logging them, as ParseWarnings does.
+func ParseStatement(body []byte) (*Statement, []Warning, error) {
+	p := Parser{Tolerance: DefaultTolerance}
+	return p.ParseWarnings(body)
+}
+
+// Parse extracts the transactions and balances from the text of a statement (as produced by
+// `pdftotext -raw -nopgbrk`), sorted with the most recent transaction first, and Reconciles them.
+//
+// In strict mode the first problem is returned on its own, with a nil Statement. Otherwise
+// malformed transactions are skipped, and every problem found is returned as an Errors
+// alongside the Statement. Either way a failed reconciliation is reported as a *ReconcileError,
+// with the Statement still returned.
+func (p *Parser) Parse(body []byte) (*Statement, error) {
+	var transactions Transactions
+	statement, err := p.parse(body, func(t Transaction) error {
+		transactions = append(transactions, t)
+		return nil
+	})
+	if statement != nil {
+		sort.Sort(transactions)
+		statement.Transactions = transactions
+	}
+	return statement, err
+}
+
+// Stream is like Parse, but rather than collecting the transactions in the Statement it passes
+// each one to fn as soon as it's parsed, in the order they're printed on the statement. This
+// keeps memory bounded on very long statements, at the cost of only reconciling once every
+// transaction has been seen. The returned Statement has no Transactions. An error from fn stops
+// parsing, and is returned with a nil Statement.
+func (p *Parser) Stream(body []byte, fn func(Transaction) error) (*Statement, error) {
+	return p.parse(body, fn)
+}
+
+// errStopped stops Stream when a range over Transactions ends early.
+var errStopped = errors.New("stopped")
+
+// Transactions returns an iterator over the transactions in body, in the order they're printed
+// on the statement, parsing them as the loop asks for them the way Stream does. Problems are
+// yielded as errors alongside a zero Transaction, the same errors Stream would return, except
+// that whether the statement reconciles is only known once the loop has been through every
+// transaction: a failure comes as the final *ReconcileError.
+func (p *Parser) Transactions(body []byte) iter.Seq2[Transaction, error] {
+	return func(yield func(Transaction, error) bool) {
+		_, err := p.Stream(body, func(t Transaction) error {
+			if !yield(t, nil) {
+				return errStopped
+			}
+			return nil
+		})
+		if err == nil || err == errStopped {
+			return
+		}
+		if errs, ok := err.(Errors); ok {
+			for _, e := range errs {
+				if !yield(Transaction{}, e) {
+					return
+				}
+			}
+			return
+		}
+		yield(Transaction{}, err)
+	}
+}
+
+// StreamTransactions iterates over the transactions in body with a non-strict Parser that allows
+// DefaultTolerance when reconciling; see Parser.Transactions.
+func StreamTransactions(body []byte) iter.Seq2[Transaction, error] {
+	p := Parser{Tolerance: DefaultTolerance}
+	return p.Transactions(body)
+}
+
+func (p *Parser) parse(body []byte, emit func(Transaction) error) (*Statement, error) {
+	statement := Statement{Currency: p.Currency}
+	if statement.Currency == "" {
+		statement.Currency = DefaultCurrency
+	}
+	layout := p.layout()
+	statement.Deposit = layout.Deposit
+	var errs Errors
+	var tl tally
+
+	// The closing date's year is the most reliable one to stamp transactions with, falling
+	// back to the year-to-date totals when the period can't be found.
+	var yearBytes []byte
+	if period := findPeriod.FindSubmatch(body); period != nil {
+		start, serr := createDate(period[2], period[1], append([]byte("20"), period[3]...), p.location())
+		end, eerr := createDate(period[5], period[4], append([]byte("20"), period[6]...), p.location())
+		if serr == nil && eerr == nil {
+			statement.PeriodStart, statement.PeriodEnd = start, end
+			yearBytes = []byte(strconv.Itoa(end.Year()))
+		}
+	}
+	if yb := layout.Year.FindSubmatch(body); len(yb) > 1 && yearBytes == nil {
+		yearBytes = yb[1]
+	}
+	if p.Year != 0 {
+		yearBytes = []byte(strconv.Itoa(p.Year))
+	} else if yearBytes == nil && p.FallbackYear != 0 {
+		p.warn(WarnGuessedYear, "", "could not find the statement year, assuming %d", p.FallbackYear)
+		yearBytes = []byte(strconv.Itoa(p.FallbackYear))
+	}
+
+	var trace *tabwriter.Writer
+	if p.Trace != nil {
+		trace = tabwriter.NewWriter(p.Trace, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(trace, "LINE\tMONTH\tDAY\tAMOUNT\tDATE\tERROR")
+	}
+	lines := joinWrapped(body)
+	if layout.Start != nil {
+		if start := layout.Start.FindIndex(lines); start != nil {
+			lines = lines[start[0]:]
+		}
+	}
+	if layout.End != nil {
+		if end := layout.End.FindIndex(lines); end != nil {
+			lines = lines[:end[0]]
+		}
+	}
+	matches := layout.Transaction.FindAllSubmatchIndex(lines, -1)
+	if len(matches) == 0 {
+		return nil, ErrNoTransactions
+	}
+	sections := findSection.FindAllSubmatchIndex(lines, -1)
+	for i, loc := range matches {
+		st := submatches(lines, loc)
+		if len(st) < 4 {
+			errs = append(errs, Warning{Code: WarnBadMatch, Message: fmt.Sprintf("bad match for match no %d", i)})
+			if p.Strict {
+				return nil, errs[0]
+			}
+			continue
+		}
+		t, err := p.parseTransaction(st, lines[loc[1]:], yearBytes, statement.PeriodEnd)
+		if trace != nil {
+			var errText string
+			if err != nil {
+				errText = err.Error()
+			}
+			fmt.Fprintf(trace, "%s\t%s\t%s\t%s\t%s\t%s\n", st[0], st[1], st[2], t.Amount, t.Date.Format("2006-01-02"), errText)
+		}
+		if err != nil {
+			err = Warning{Code: WarnBadTransaction, Message: err.Error(), Line: string(st[0])}
+			errs = append(errs, err)
+			if p.Strict {
+				return nil, err
+			}
+			continue
+		}
+		if t.Amount > 0 && strings.HasPrefix(section(lines, sections, loc[0]), "CASH ADVANCE") {
+			t.Kind = CashAdvance
+		}
+		if p.KeepRaw {
+			t.RawLine = string(st[0])
+		}
+		if err := emit(t); err != nil {
+			return nil, err
+		}
+		tl.add(t)
+	}
+	if trace != nil {
+		trace.Flush()
+	}
+	// Not one line reading is down to the Layout or NumberFormat rather than the odd bad line,
+	// and leaves nothing to reconcile.
+	if tl.count == 0 && errs != nil {
+		return nil, fmt.Errorf("none of the %d transactions could be read, the first: %v", len(matches), errs[0])
+	}
+	// Reconciliation can't catch two parse errors that cancel out, so independently count the
+	// lines that look like transactions as a second check.
+	if dated := countDatedLines(lines); dated != len(matches) {
+		err := Warning{Code: WarnUncountedLines, Message: fmt.Sprintf("found %d dated lines but only %d transactions", dated, len(matches))}
+		errs = append(errs, err)
+		if p.Strict {
+			return nil, err
+		}
+	}
+	var prevErr error
+	if amt, err := p.findBalance(body, layout.PreviousBalance, "Previous Balance"); err != nil {
+		prevErr = err
+	} else {
+		statement.StartingBalance = amt
+	}
+	if amt, err := p.findBalance(body, layout.NewBalance, "New Balance"); err != nil {
+		errs = append(errs, err)
+		if p.Strict {
+			return nil, err
+		}
+	} else {
+		statement.EndingBalance = amt
+	}
+	// Consolidated statements can hold several balance sections, and only the first is used.
+	// Checking statements repeat the same Ending Balance beneath their transactions, so only
+	// differing balances count.
+	if n := countBalances(body, layout.NewBalance); n > 1 {
+		err := Warning{Code: WarnMultipleBalances, Message: fmt.Sprintf("found %d different New Balance lines, using the first; Split the text to parse each statement", n)}
+		errs = append(errs, err)
+		if p.Strict {
+			return nil, err
+		}
+	}
+	// The interest and fees summaries are optional; plenty of statements have neither.
+	if amt, err := p.findBalance(body, findInterestCharged, "Interest Charged"); err == nil {
+		statement.InterestCharged = amt
+	}
+	if amt, err := p.findBalance(body, findFeesCharged, "Fees Charged"); err == nil {
+		statement.FeesCharged = amt
+	}
+	if amt, err := p.findBalance(body, findCashAdvances, "Cash Advances"); err == nil {
+		statement.CashAdvances = amt
+		statement.subtotals.cashAdvances = true
+	}
+	if amt, err := p.findBalance(body, findPayments, "Payment, Credits"); err == nil {
+		statement.PaymentsAndCredits = -amt
+		statement.subtotals.payments = true
+	}
+	if amt, err := p.findBalance(body, findPurchases, "Purchases"); err == nil {
+		statement.Purchases = amt
+		statement.subtotals.purchases = true
+	}
+	if amt, err := p.findBalance(body, findCreditLimit, "Credit Limit"); err == nil {
+		statement.CreditLimit = amt
+	}
+	if amt, err := p.findBalance(body, findAvailableCredit, "Available Credit"); err == nil {
+		statement.AvailableCredit = amt
+	}
+	if amt, err := p.findBalance(body, findMinimumPayment, "Minimum Payment Due"); err == nil {
+		statement.MinimumPaymentDue = amt
+	}
+	if due := findPaymentDueDate.FindSubmatch(body); due != nil {
+		if d, err := createDate(due[2], due[1], append([]byte("20"), due[3]...), p.location()); err == nil {
+			statement.PaymentDueDate = d
+		}
+	}
+	statement.RewardsEarned = findRewards(body, findRewardsEarned)
+	statement.RewardsRedeemed = findRewards(body, findRewardsRedeemed)
+	if acct := findAccountNumber.FindSubmatch(body); acct != nil {
+		statement.AccountNumber = string(acct[1])
+	}
+	// The first statement of a new card has no Previous Balance, which is told apart from a
+	// layout that's stopped matching by transactions having been found, and, unless NewAccount
+	// says so, by them adding up to the New Balance from zero.
+	if prevErr != nil {
+		if _, ok := statement.reconcile(tl, p.Tolerance); tl.count > 0 && (p.NewAccount || ok) {
+			if !p.NewAccount {
+				p.warn(WarnNewAccount, "", "could not find Previous Balance, treating this as a new account's first statement")
+			}
+		} else {
+			errs = append(errs, prevErr)
+			if p.Strict {
+				return nil, prevErr
+			}
+		}
+	}
+	if val, res := statement.reconcile(tl, p.Tolerance); !res {
+		rerr := &ReconcileError{Actual: val, Expected: statement.EndingBalance}
+		if errs == nil {
+			return &statement, rerr
+		}
+		errs = append(errs, rerr)
+	}
+	if errs != nil {
+		return &statement, errs
+	}
+	return &statement, nil
+}
+
+func (p *Parser) location() *time.Location {
+	if p.Location != nil {
+		return p.Location
+	}
+	return time.UTC
+}
+
+func (p *Parser) layout() *Layout {
+	if p.Layout != nil {
+		return p.Layout
+	}
+	return CreditCard
+}
+
+// parseTransaction builds a Transaction from the submatches of a Layout.Transaction match, where
+// rest is the text following the match.
+func (p *Parser) parseTransaction(st [][]byte, rest []byte, yearBytes []byte, periodEnd time.Time) (Transaction, error) {
+	var t Transaction
+	// Invalid UTF-8 would make for a malformed CSV, so it's replaced rather than passed through.
+	t.MerchantName = strings.ToValidUTF8(string(st[3]), "\uFFFD")
+	t.ForeignFee = findForeignFee.MatchString(t.MerchantName)
+	amt, err := p.amount(string(st[4]))
+	if err != nil {
+		return t, fmt.Errorf("bad amount parse for \"%s\": %v", t.MerchantName, err)
+	}
+	if p.layout().Deposit {
+		amt = -amt
+	}
+	t.Amount = amt
+	month, day := st[1], st[2]
+	// A "month" past 12 is most likely a day/month date, from a layout variant or a misread.
+	if m, _ := strconv.Atoi(string(month)); m > 12 {
+		if d, _ := strconv.Atoi(string(day)); d > 12 {
+			return t, fmt.Errorf("bad date parse for \"%s\": neither %s nor %s can be a month", t.MerchantName, month, day)
+		}
+		p.warn(WarnSwappedDate, "", "\"%s\" is dated %s/%s, reading it as day/month", t.MerchantName, month, day)
+		month, day = day, month
+	}
+	d, err := createDate(day, month, yearBytes, p.location())
+	if err != nil {
+		return t, fmt.Errorf("bad date parse for \"%s\": %v", t.MerchantName, err)
+	}
+	// Transactions are stamped with the closing date's year, so on a statement that closes
+	// in January, December's transactions land in the future and belong to the year before.
+	if p.Year == 0 && !periodEnd.IsZero() && d.After(periodEnd) {
+		d = d.AddDate(-1, 0, 0)
+	}
+	t.Date = d
+	// Some statements print the posting date after the transaction date.
+	if post := findPostDate.FindStringSubmatch(t.MerchantName); post != nil {
+		if pd, err := createDate([]byte(post[2]), []byte(post[1]), []byte(strconv.Itoa(d.Year())), p.location()); err == nil {
+			if pd.Before(d) {
+				pd = pd.AddDate(1, 0, 0)
+			}
+			t.PostDate = pd
+			t.MerchantName = t.MerchantName[len(post[0]):]
+		}
+	}
+	if ref := findReference.FindStringSubmatch(t.MerchantName); ref != nil {
+		t.Reference = ref[1] + ref[2]
+		t.MerchantName = strings.Replace(t.MerchantName, ref[0], "", 1)
+	}
+	if fx := findForeignCurrency.FindSubmatch(rest); fx != nil {
+		if orig, err := p.amount(string(fx[2])); err == nil {
+			t.OriginalAmount = orig
+			t.OriginalCurrency = string(fx[1])
+		}
+	}
+	t.Kind = inferKind(&t, p.layout().Deposit)
+	return t, nil
+}
+
+// section returns the findSection heading that the text at pos in lines falls under, or "" if
+// there's none before it.
+func section(lines []byte, sections [][]int, pos int) string {
+	var heading string
+	for _, loc := range sections {
+		if loc[0] > pos {
+			break
+		}
+		heading = string(lines[loc[2]:loc[3]])
+	}
+	return heading
+}
+
+// inferKind classifies a transaction from its merchant text, within the charge or credit side
+// its sign puts it on. Credits to a deposit account are deposits, which count as payments in.
+func inferKind(t *Transaction, deposit bool) Kind {
+	if t.Amount < 0 {
+		if deposit || findPayment.MatchString(t.MerchantName) {
+			return Payment
+		}
+		return Return
+	}
+	switch {
+	case findInterest.MatchString(t.MerchantName):
+		return Interest
+	case t.ForeignFee || findFee.MatchString(t.MerchantName):
+		return Fee
+	}
+	return Sale
+}
+
+var (
+	findDatedLine      = regexp.MustCompile(`^[0-9]{1,2}/[0-9]{1,2} `)
+	findTrailingAmount = regexp.MustCompile(` \(?-?\$?[0-9,]*\.[0-9]{2}\)?-?$`)
+	findAmountLine     = regexp.MustCompile(`^\(?-?\$?[0-9,]*\.[0-9]{2}\)?-?$`)
+)
+
+// joinWrapped rejoins transaction lines whose merchant description pdftotext wrapped onto the
+// next line: a dated line with no amount at its end, followed by an undated line that ends in one.
+// A long amount can be pushed onto the next line on its own, which is rejoined the same way.
+func joinWrapped(body []byte) []byte {
+	lines := bytes.Split(body, []byte("\n"))
+	joined := make([][]byte, 0, len(lines))
+	for i := 0; i < len(lines); i++ {
+		line := bytes.TrimRight(lines[i], " \t\r")
+		if i+1 < len(lines) && findDatedLine.Match(line) && !findTrailingAmount.Match(line) {
+			next := bytes.TrimSpace(lines[i+1])
+			if !findDatedLine.Match(next) && (findTrailingAmount.Match(next) || findAmountLine.Match(next)) {
+				line = append(append(append([]byte(nil), line...), ' '), next...)
+				i++
+			}
+		}
+		joined = append(joined, line)
+	}
+	return bytes.Join(joined, []byte("\n"))
+}
+
+// findCurrencyLine matches the dated currency line of a foreign transaction, which is the only
+// kind of dated line that isn't itself a transaction.
+var findCurrencyLine = regexp.MustCompile(`(?m)^[0-9]{2}/[0-9]{2} [A-Z][A-Z ]*[A-Z]\n[0-9\.,]+ X [0-9\.]+ \(EXCHG RATE\)`)
+
+// countDatedLines counts the lines in body that open with a month/day date, an estimate of the
+// number of transactions on the statement that doesn't rely on the Layout's Transaction pattern.
+func countDatedLines(body []byte) int {
+	var n int
+	for _, line := range bytes.Split(body, []byte("\n")) {
+		if findDatedLine.Match(line) {
+			n++
+		}
+	}
+	return n - len(findCurrencyLine.FindAllIndex(body, -1))
+}
+
+// submatches slices the submatches located by loc out of b, in the same shape as FindSubmatch.
+func submatches(b []byte, loc []int) [][]byte {
+	st := make([][]byte, len(loc)/2)
+	for i := range st {
+		if loc[2*i] >= 0 {
+			st[i] = b[loc[2*i]:loc[2*i+1]]
+		}
+	}
+	return st
+}
+
+// findBalance parses the balance captured by re, naming it in any error.
+func (p *Parser) findBalance(body []byte, re *regexp.Regexp, name string) (Cents, error) {
+	m := re.FindSubmatch(body)
+	if m == nil {
+		return 0, Warning{Code: WarnMissingBalance, Message: fmt.Sprintf("could not find %s", name)}
+	}
+	if len(m) < 2 {
+		return 0, fmt.Errorf("the %s pattern has no submatch for the amount", name)
+	}
+	amt, err := p.amount(string(m[1]))
+	if err != nil {
+		return 0, Warning{Code: WarnMissingBalance, Message: fmt.Sprintf("error with %s: %v", name, err), Line: string(m[0])}
+	}
+	return amt, nil
+}
+
+// countBalances counts the different balances re matches.
+func countBalances(body []byte, re *regexp.Regexp) int {
+	seen := make(map[string]bool)
+	for _, m := range re.FindAllSubmatch(body, -1) {
+		if len(m) > 1 {
+			seen[string(m[1])] = true
+		}
+	}
+	return len(seen)
+}
+
+// findRewards parses the rewards total captured by re, or returns zero if there isn't one.
+func findRewards(body []byte, re *regexp.Regexp) float64 {
+	m := re.FindSubmatch(body)
+	if m == nil {
+		return 0
+	}
+	n, err := strconv.ParseFloat(strings.Replace(string(m[1]), ",", "", -1), 64)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+var findCurrencySymbol = regexp.MustCompile(`^([\(-]?)(?:US\$|USD ?|\$)`)
+
+// amount parses an amount as printed on the statement, following the Parser's NumberFormat and
+// Rounding.
+func (p *Parser) amount(s string) (Cents, error) {
+	return sanitizeAmount(p.NumberFormat.usDigits(s), p.Rounding)
+}
+
+// sanitizeAmount parses an amount as printed on a statement, where credits may be written
+// with a leading minus, a trailing minus ("12.34-") or in parentheses ("(12.34)"), and the
+// amount may carry a currency symbol ("$12.34", "-$12.34", "USD 12.34"). Whole-dollar amounts
+// ("1,234") are read as such.
+func sanitizeAmount(amtString string, rounding Rounding) (Cents, error) {
+	amtString = findCurrencySymbol.ReplaceAllString(amtString, "$1")
+	var negative bool
+	if len(amtString) > 2 && strings.HasPrefix(amtString, "(") && strings.HasSuffix(amtString, ")") {
+		amtString = amtString[1 : len(amtString)-1]
+		negative = true
+	} else if len(amtString) > 1 && strings.HasSuffix(amtString, "-") {
+		amtString = amtString[:len(amtString)-1]
+		negative = true
+	}
+	amtString, err := ungroup(amtString)
+	if err != nil {
+		return 0, err
+	}
+	cents, err := parseCents(amtString, rounding)
+	if err != nil {
+		return 0, err
+	}
+	if negative {
+		cents = -cents
+	}
+	return cents, nil
+}
+
+// ungroup drops the thousands separators from amt, which have to fall every three digits of the
+// dollars: just dropping every "," would read "1,23" as 123.00, so that's refused instead of
+// guessed at.
+func ungroup(amt string) (string, error) {
+	whole, frac, dot := strings.Cut(amt, ".")
+	groups := strings.Split(whole, ",")
+	if len(groups) == 1 {
+		return amt, nil
+	}
+	for i, g := range groups {
+		if i == 0 {
+			g = strings.TrimLeft(g, "+-")
+		}
+		if i == 0 && (len(g) == 0 || len(g) > 3) || i > 0 && len(g) != 3 {
+			return "", fmt.Errorf("invalid amount %q: its digits aren't grouped in thousands", amt)
+		}
+	}
+	whole = strings.Join(groups, "")
+	if dot {
+		return whole + "." + frac, nil
+	}
+	return whole, nil
+}
+
+// parseCents parses a decimal amount straight into whole cents, rounding any further digits as
+// rounding says. Working from the digits avoids the binary representation of amounts like 2.675
+// (really 2.67499999...) rounding the wrong way.
+func parseCents(amt string, rounding Rounding) (Cents, error) {
+	var negative bool
+	switch {
+	case strings.HasPrefix(amt, "-"):
+		negative = true
+		amt = amt[1:]
+	case strings.HasPrefix(amt, "+"):
+		amt = amt[1:]
+	}
+	whole, frac, _ := strings.Cut(amt, ".")
+	if whole == "" && frac == "" || !isDigits(whole) || !isDigits(frac) {
+		return 0, fmt.Errorf("invalid amount %q", amt)
+	}
+	var cents Cents
+	if whole != "" {
+		n, err := strconv.ParseInt(whole, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		cents = Cents(n) * 100
+	}
+	frac += "000"
+	cents += Cents(frac[0]-'0')*10 + Cents(frac[1]-'0')
+	switch rest := strings.TrimRight(frac[2:], "0"); {
+	case rounding == RoundTruncate || rest == "":
+	case rest[0] > '5' || rest[0] == '5' && (len(rest) > 1 || rounding == RoundHalfUp):
+		cents++
+	case rest == "5" && cents%2 == 1:
+		// Exactly half a cent, which RoundHalfEven rounds to the even neighbour.
+		cents++
+	}
+	if negative {
+		cents = -cents
+	}
+	return cents, nil
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func createDate(day, month, year []byte, loc *time.Location) (time.Time, error) {
+	var t time.Time
+	var d, m, y int
+	if day == nil || month == nil || year == nil {
+		return t, errors.New("a piece of the date is missing")
+	}
+
+	d, err := strconv.Atoi(string(day))
+	if err != nil {
+		return t, err
+	}
+
+	m, err = strconv.Atoi(string(month))
+	if err != nil {
+		return t, err
+	}
+
+	y, err = strconv.Atoi(string(year))
+	if err != nil {
+		return t, err
+	}
+
+	t = time.Date(y, time.Month(m), d, 0, 0, 0, 0, loc)
+	// time.Date normalizes dates like 02/30 into March, which would hide a bad capture.
+	if t.Month() != time.Month(m) || t.Day() != d {
+		return time.Time{}, fmt.Errorf("%02d/%02d/%d isn't a real date", m, d, y)
+	}
+	return t, nil
+}
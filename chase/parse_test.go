@@ -0,0 +1,427 @@
+package chase
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// find returns the transaction with merchant, or nil.
+func find(s *Statement, merchant string) *Transaction {
+	if s == nil {
+		return nil
+	}
+	for i := range s.Transactions {
+		if s.Transactions[i].MerchantName == merchant {
+			return &s.Transactions[i]
+		}
+	}
+	return nil
+}
+
+func TestParseStatement(t *testing.T) {
+	type known struct {
+		merchant string
+		date     time.Time
+		amount   Cents
+		kind     Kind
+	}
+	tests := []struct {
+		file             string
+		layout           *Layout
+		transactions     int
+		starting, ending Cents
+		periodStart, end time.Time
+		known            []known
+	}{
+		{
+			file:         "january.txt",
+			transactions: 5,
+			starting:     100000,
+			ending:       63345,
+			periodStart:  date(2017, time.December, 3),
+			end:          date(2018, time.January, 2),
+			known: []known{
+				{"Payment Thank You - Web", date(2017, time.December, 15), -50000, Payment},
+				{"AMAZON MKTP US*1A2B3C4D5 AMZN.COM/BILLWA", date(2017, time.December, 5), 2345, Sale},
+				{"FOREIGN TRANSACTION FEE", date(2017, time.December, 19), 163, Fee},
+				{"WHOLE FOODS MARKET #10234 NEW YORK NY", date(2018, time.January, 1), 4415, Sale},
+			},
+		},
+		{
+			file:         "june.txt",
+			transactions: 6,
+			starting:     234567,
+			ending:       132362,
+			periodStart:  date(2024, time.May, 15),
+			end:          date(2024, time.June, 14),
+			known: []known{
+				{"AUTOMATIC PAYMENT - THANK YOU", date(2024, time.May, 28), -234567, Payment},
+				{"DELTA AIR LINES ATLANTA", date(2024, time.May, 22), 123456, Sale},
+				{"PURCHASE INTEREST CHARGE", date(2024, time.June, 14), 1234, Interest},
+			},
+		},
+		{
+			file:         "checking.txt",
+			layout:       Checking,
+			transactions: 3,
+			starting:     123456,
+			ending:       313006,
+			known: []known{
+				{"Payroll Direct Dep", date(2018, time.January, 5), -200000, Payment},
+				{"Con Ed Bill Pay", date(2018, time.January, 9), 10000, Sale},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.file, func(t *testing.T) {
+			body := readFixture(t, test.file)
+			var s *Statement
+			var warnings []Warning
+			var err error
+			if test.layout == nil {
+				s, warnings, err = ParseStatement(body)
+			} else {
+				p := Parser{Layout: test.layout, Tolerance: DefaultTolerance}
+				s, warnings, err = p.ParseWarnings(body)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, w := range warnings {
+				t.Errorf("warning: %s: %v", w.Code, w)
+			}
+			if len(s.Transactions) != test.transactions {
+				t.Errorf("got %d transactions, want %d", len(s.Transactions), test.transactions)
+			}
+			if s.StartingBalance != test.starting || s.EndingBalance != test.ending {
+				t.Errorf("got balances %s and %s, want %s and %s", s.StartingBalance, s.EndingBalance, test.starting, test.ending)
+			}
+			if !s.PeriodStart.Equal(test.periodStart) || !s.PeriodEnd.Equal(test.end) {
+				t.Errorf("got period %v - %v, want %v - %v", s.PeriodStart, s.PeriodEnd, test.periodStart, test.end)
+			}
+			if total, ok := s.Reconcile(); !ok {
+				t.Errorf("doesn't reconcile: total %s, New Balance %s", total, s.EndingBalance)
+			}
+			for _, k := range test.known {
+				tr := find(s, k.merchant)
+				if tr == nil {
+					t.Errorf("no transaction %q", k.merchant)
+					continue
+				}
+				if !tr.Date.Equal(k.date) || tr.Amount != k.amount || tr.Kind != k.kind {
+					t.Errorf("%q: got %s %s %s, want %s %s %s", k.merchant, tr.Date.Format("2006-01-02"), tr.Amount, tr.Kind,
+						k.date.Format("2006-01-02"), k.amount, k.kind)
+				}
+			}
+			for i := 1; i < len(s.Transactions); i++ {
+				if s.Transactions[i].Date.After(s.Transactions[i-1].Date) {
+					t.Errorf("transactions aren't sorted newest first: %s follows %s",
+						s.Transactions[i].Date.Format("2006-01-02"), s.Transactions[i-1].Date.Format("2006-01-02"))
+				}
+			}
+		})
+	}
+}
+
+// statementText builds the text of a minimal credit card statement.
+func statementText(previous, new, period string, activity ...string) []byte {
+	text := "ACCOUNT SUMMARY\nPrevious Balance $" + previous + "\nNew Balance $" + new + "\n"
+	if period != "" {
+		text += "Opening/Closing Date " + period + "\n"
+	}
+	text += "ACCOUNT ACTIVITY\n"
+	for _, line := range activity {
+		text += line + "\n"
+	}
+	return []byte(text)
+}
+
+// TestYearRollover checks that a statement closing in January dates December's transactions in
+// the year before, using the statement period rather than a single year.
+func TestYearRollover(t *testing.T) {
+	tests := []struct {
+		name   string
+		period string
+		year   int
+		want   map[string]time.Time
+	}{
+		{"january", "12/03/17 - 01/02/18", 0, map[string]time.Time{
+			"DECEMBER": date(2017, time.December, 15),
+			"JANUARY":  date(2018, time.January, 1),
+		}},
+		{"december", "11/03/17 - 12/02/17", 0, map[string]time.Time{
+			"DECEMBER": date(2017, time.December, 1),
+			"NOVEMBER": date(2017, time.November, 15),
+		}},
+		// An explicit Year is taken as it is.
+		{"explicit year", "12/03/17 - 01/02/18", 2020, map[string]time.Time{
+			"DECEMBER": date(2020, time.December, 15),
+			"JANUARY":  date(2020, time.January, 1),
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var lines []string
+			for merchant, d := range test.want {
+				lines = append(lines, d.Format("01/02 ")+merchant+" 10.00")
+			}
+			p := Parser{Year: test.year}
+			s, err := p.Parse(statementText("0.00", "20.00", test.period, lines...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for merchant, want := range test.want {
+				if tr := find(s, merchant); tr == nil {
+					t.Errorf("no transaction %q", merchant)
+				} else if !tr.Date.Equal(want) {
+					t.Errorf("%q is dated %s, want %s", merchant, tr.Date.Format("2006-01-02"), want.Format("2006-01-02"))
+				}
+			}
+		})
+	}
+	// The January fixture holds December transactions too.
+	s, _, err := ParseStatement(readFixture(t, "january.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tr := range s.Transactions {
+		want := 2017
+		if tr.Date.Month() == time.January {
+			want = 2018
+		}
+		if tr.Date.Year() != want {
+			t.Errorf("%q is dated %s, want it in %d", tr.MerchantName, tr.Date.Format("2006-01-02"), want)
+		}
+	}
+}
+
+func TestSanitizeAmount(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Cents
+		invalid bool
+	}{
+		{in: "1,234.56", want: 123456},
+		{in: "(1,234.56)", want: -123456},
+		{in: "1,234.56-", want: -123456},
+		{in: "-1,234.56", want: -123456},
+		{in: "(12.34)", want: -1234},
+		{in: "12.34-", want: -1234},
+		{in: "$12.34", want: 1234},
+		{in: "-$12.34", want: -1234},
+		{in: "($1,234.56)", want: -123456},
+		{in: "US$12.34", want: 1234},
+		{in: "USD 12.34", want: 1234},
+		{in: "USD12.34", want: 1234},
+		{in: "$", invalid: true},
+		{in: "1,234", want: 123400},
+		{in: "1234", want: 123400},
+		{in: "0", want: 0},
+		{in: "$1,234,567", want: 123456700},
+		{in: "1,23", invalid: true},
+		{in: "1,2345.00", invalid: true},
+		{in: ",123", invalid: true},
+		{in: "-", invalid: true},
+		{in: "()", invalid: true},
+	}
+	for _, test := range tests {
+		got, err := sanitizeAmount(test.in, RoundHalfUp)
+		if test.invalid {
+			if err == nil {
+				t.Errorf("%q: got %s, want an error", test.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", test.in, err)
+		} else if got != test.want {
+			t.Errorf("%q: got %s, want %s", test.in, got, test.want)
+		}
+	}
+}
+
+// TestRounding pins how amounts with more than two decimal places are rounded, which is done
+// from the digits, so 2.675 isn't taken for the 2.67499999... that it is as a float.
+func TestRounding(t *testing.T) {
+	tests := []struct {
+		in               string
+		halfUp, halfEven Cents
+		truncate         Cents
+	}{
+		{"2.675", 268, 268, 267},
+		{"-2.675", -268, -268, -267},
+		{"2.665", 267, 266, 266},
+		{"0.005", 1, 0, 0},
+		{"-0.005", -1, 0, 0},
+		{"0.0051", 1, 1, 0},
+		{"2.67", 267, 267, 267},
+	}
+	for _, test := range tests {
+		for _, r := range []struct {
+			rounding Rounding
+			want     Cents
+		}{{RoundHalfUp, test.halfUp}, {RoundHalfEven, test.halfEven}, {RoundTruncate, test.truncate}} {
+			if got, err := sanitizeAmount(test.in, r.rounding); err != nil {
+				t.Errorf("%q: %v", test.in, err)
+			} else if got != r.want {
+				t.Errorf("%q rounded with %d: got %s, want %s", test.in, r.rounding, got, r.want)
+			}
+		}
+	}
+}
+
+// TestReconcileInCents checks that amounts which don't add up exactly as floats still reconcile.
+func TestReconcileInCents(t *testing.T) {
+	var lines []string
+	for i := 0; i < 10; i++ {
+		lines = append(lines, "01/05 SHOP 0.10")
+	}
+	s, err := (&Parser{}).Parse(statementText("0.00", "1.00", "01/01/24 - 01/31/24", lines...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total, ok := s.Reconcile(); !ok {
+		t.Errorf("ten 0.10s total %s, not 1.00", total)
+	}
+}
+
+// TestNumberFormats checks that whole-dollar amounts keep their magnitude whichever way the
+// statement groups its digits.
+func TestNumberFormats(t *testing.T) {
+	tests := []struct {
+		format NumberFormat
+		in     string
+		want   Cents
+	}{
+		{NumbersUS, "1,234", 123400},
+		{NumbersUS, "1234", 123400},
+		{NumbersUS, "0", 0},
+		{NumbersEU, "1.234", 123400},
+		{NumbersEU, "1.234,56", 123456},
+		{NumbersAuto, "1,234", 123400},
+		{NumbersAuto, "1.234,56", 123456},
+		{NumbersAuto, "1,23", 123},
+		{NumbersAuto, "0", 0},
+	}
+	for _, test := range tests {
+		p := Parser{NumberFormat: test.format}
+		if got, err := p.amount(test.in); err != nil {
+			t.Errorf("%q in format %d: %v", test.in, test.format, err)
+		} else if got != test.want {
+			t.Errorf("%q in format %d: got %s, want %s", test.in, test.format, got, test.want)
+		}
+	}
+	// Whole-dollar amounts are still written with their cents.
+	if got := Cents(123400).String(); got != "1234.00" {
+		t.Errorf("got %q, want 1234.00", got)
+	}
+}
+
+// TestNoRewardsSection checks that without an "Amount Rewards" line to end the account activity
+// at, the interest section's figures aren't picked up as transactions.
+func TestNoRewardsSection(t *testing.T) {
+	body := readFixture(t, "no-rewards.txt")
+	tests := []struct {
+		name string
+		body []byte
+	}{
+		{"interest charges", body},
+		{"year-to-date totals", bytes.Replace(body, []byte("INTEREST CHARGES\n"), []byte("2024 Totals Year-to-Date\nINTEREST CHARGES\n"), 1)},
+	}
+	for _, test := range tests {
+		s, warnings, err := ParseStatement(test.body)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		for _, w := range warnings {
+			t.Errorf("%s: warning: %v", test.name, w)
+		}
+		if len(s.Transactions) != 3 {
+			t.Errorf("%s: got %d transactions, want 3", test.name, len(s.Transactions))
+		}
+		if tr := find(s, "Balance Subject To Interest Rate"); tr != nil {
+			t.Errorf("%s: the interest section was parsed as a transaction of %s", test.name, tr.Amount)
+		}
+	}
+}
+
+// TestRewards checks that the rewards summary is found however it's worded, including the dated
+// line in june.txt.
+func TestRewards(t *testing.T) {
+	withRewards := func(lines string) []byte {
+		return append(statementText("0.00", "5.00", "05/01/24 - 05/31/24", "05/20 STORE 5.00"), "Amount Rewards\n"+lines+"\n"...)
+	}
+	tests := []struct {
+		name             string
+		body             []byte
+		earned, redeemed float64
+	}{
+		{"june.txt", readFixture(t, "june.txt"), 1311, 0},
+		{"cash back", withRewards("Cash back earned this period +$5.54\nCash back redeemed -$20.00"), 5.54, 20},
+		{"points", withRewards("Total points earned 1,500\nPoints redeemed 5,000"), 1500, 5000},
+		{"dated", withRewards("06/14 Points earned this period 250\n06/14/2024 Points redeemed 100"), 250, 100},
+		{"none", withRewards(""), 0, 0},
+	}
+	for _, test := range tests {
+		s, _, err := ParseStatement(test.body)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if s.RewardsEarned != test.earned || s.RewardsRedeemed != test.redeemed {
+			t.Errorf("%s: got %v earned and %v redeemed, want %v and %v", test.name, s.RewardsEarned, s.RewardsRedeemed, test.earned, test.redeemed)
+		}
+	}
+}
+
+func TestSwappedDates(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    time.Time
+		warning WarningCode
+	}{
+		{"05/13 SHOP 10.00", date(2024, time.May, 13), ""},
+		{"13/05 SHOP 10.00", date(2024, time.May, 13), WarnSwappedDate},
+		{"12/05 SHOP 10.00", date(2024, time.December, 5), ""},
+		{"13/14 SHOP 10.00", time.Time{}, WarnBadTransaction},
+	}
+	for _, test := range tests {
+		p := Parser{Year: 2024}
+		// Another transaction keeps the statement readable when the one tested isn't.
+		s, warnings, _ := p.ParseWarnings(statementText("0.00", "15.00", "", test.line, "05/20 OTHER 5.00"))
+		var codes []WarningCode
+		for _, w := range warnings {
+			codes = append(codes, w.Code)
+		}
+		if test.warning == "" && len(codes) > 0 || test.warning != "" && (len(codes) == 0 || codes[0] != test.warning) {
+			t.Errorf("%q: got warnings %v, want %q", test.line, codes, test.warning)
+		}
+		tr := find(s, "SHOP")
+		if test.want.IsZero() {
+			if tr != nil {
+				t.Errorf("%q: got a transaction dated %s, want none", test.line, tr.Date.Format("2006-01-02"))
+			}
+		} else if tr == nil {
+			t.Errorf("%q: got no transaction", test.line)
+		} else if !tr.Date.Equal(test.want) {
+			t.Errorf("%q: dated %s, want %s", test.line, tr.Date.Format("2006-01-02"), test.want.Format("2006-01-02"))
+		}
+	}
+}
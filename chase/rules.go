@@ -0,0 +1,34 @@
+package chase
+
+import "regexp"
+
+// Uncategorized is the Category given to transactions that no Rule matches.
+const Uncategorized = "Uncategorized"
+
+// Rule assigns Category to the transactions whose merchant matches Pattern.
+type Rule struct {
+	Pattern  *regexp.Regexp
+	Category string
+}
+
+// Rules is an ordered list of categorization rules, where the first match wins.
+type Rules []Rule
+
+// Category returns the category of the first rule matching t's merchant name, raw or
+// normalized, or Uncategorized.
+func (r Rules) Category(t *Transaction) string {
+	for _, rule := range r {
+		if rule.Pattern.MatchString(t.MerchantName) ||
+			(t.NormalizedMerchant != "" && rule.Pattern.MatchString(t.NormalizedMerchant)) {
+			return rule.Category
+		}
+	}
+	return Uncategorized
+}
+
+// Categorize fills in the Category of each of the statement's transactions.
+func (s *Statement) Categorize(rules Rules) {
+	for i := range s.Transactions {
+		s.Transactions[i].Category = rules.Category(&s.Transactions[i])
+	}
+}
@@ -0,0 +1,71 @@
+package chase
+
+import "sort"
+
+// Split breaks text holding several concatenated statements into one slice per statement, so each
+// can be parsed on its own. A new statement starts at each of layout's PreviousBalance lines that
+// follows some transactions; any text before a statement's PreviousBalance line stays with the
+// statement before it, which the parser ignores. Text holding a single statement is returned
+// whole. A nil layout means CreditCard.
+func Split(body []byte, layout *Layout) [][]byte {
+	if layout == nil {
+		layout = CreditCard
+	}
+	locs := layout.PreviousBalance.FindAllIndex(body, -1)
+	if len(locs) < 2 {
+		return [][]byte{body}
+	}
+	var parts [][]byte
+	start := 0
+	for _, loc := range locs[1:] {
+		// Checking statements repeat the Beginning Balance above their transactions, so a
+		// statement only ends once some transactions have been seen.
+		if !layout.Transaction.Match(body[start:loc[0]]) {
+			continue
+		}
+		parts = append(parts, body[start:loc[0]])
+		start = loc[0]
+	}
+	return append(parts, body[start:])
+}
+
+// Account is one account's part of a combined statement, as returned by SplitAccounts.
+type Account struct {
+	Layout *Layout
+	Body   []byte
+}
+
+// SplitAccounts breaks a combined statement, covering say a credit card and a checking account,
+// into one part per account, each with the layout to parse it with. A new part starts at each of
+// layouts' Header lines that follows the Header of a different layout, so consecutive statements
+// of one layout stay together for Split; text before the first Header goes with the first part.
+// Text without Headers of more than one layout is returned whole, with layouts[0].
+func SplitAccounts(body []byte, layouts ...*Layout) []Account {
+	type header struct {
+		pos    int
+		layout *Layout
+	}
+	var headers []header
+	for _, l := range layouts {
+		if l.Header == nil {
+			continue
+		}
+		for _, loc := range l.Header.FindAllIndex(body, -1) {
+			headers = append(headers, header{loc[0], l})
+		}
+	}
+	sort.Slice(headers, func(i, j int) bool { return headers[i].pos < headers[j].pos })
+	var accounts []Account
+	start := 0
+	for i, h := range headers {
+		if i == 0 || h.layout == headers[i-1].layout {
+			continue
+		}
+		accounts = append(accounts, Account{headers[i-1].layout, body[start:h.pos]})
+		start = h.pos
+	}
+	if accounts == nil {
+		return []Account{{layouts[0], body}}
+	}
+	return append(accounts, Account{headers[len(headers)-1].layout, body[start:]})
+}
@@ -0,0 +1,382 @@
+// Package chase parses the text of Chase credit card statement PDF's (as extracted by
+// `pdftotext -raw`) into a Statement of transactions.
+package chase
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"sort"
+	"time"
+)
+
+// Kind classifies a transaction more finely than the Sale/Payment split in Values(). Sale,
+// CashAdvance, Interest and Fee transactions are always charges, and Payment and Return transactions are always
+// credits, so each Kind maps onto the same Sale/Payment type that Values() derives from the sign.
+type Kind int
+
+const (
+	Sale Kind = iota
+	Payment
+	Return
+	Interest
+	Fee
+	// CashAdvance is a charge listed under the statement's CASH ADVANCES heading, which
+	// accrues interest differently from purchases.
+	CashAdvance
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Payment:
+		return "Payment"
+	case Return:
+		return "Return"
+	case Interest:
+		return "Interest"
+	case Fee:
+		return "Fee"
+	case CashAdvance:
+		return "Cash Advance"
+	default:
+		return "Sale"
+	}
+}
+
+// Transaction represents a basic statement transaction, as shown by Chase in their Credit Card PDF statements.
+type Transaction struct {
+	Amount       Cents
+	MerchantName string
+	Date         time.Time
+
+	// PostDate is when the transaction posted, if the statement prints it separately from Date.
+	PostDate time.Time
+
+	// NormalizedMerchant is the cleaned up merchant name filled in by Statement.Normalize.
+	NormalizedMerchant string
+
+	// OriginalAmount and OriginalCurrency are set for foreign currency transactions, holding
+	// the amount in the currency it was charged in (as printed, e.g. "EURO") before conversion.
+	OriginalAmount   Cents
+	OriginalCurrency string
+
+	// ForeignFee marks the "Foreign Transaction Fee" line items Chase adds after each
+	// international purchase.
+	ForeignFee bool
+
+	Kind Kind
+
+	// Source names the statement file the transaction came from, for tracing rows of merged
+	// output back to it. The parser leaves it empty for the caller to fill in.
+	Source string
+
+	// Reference is the reference number some layouts print on each transaction line, or ""
+	// if there isn't one.
+	Reference string
+
+	// Category is the spending category assigned by Statement.Categorize.
+	Category string
+
+	// RawLine is the statement text the transaction was matched from, when Parser.KeepRaw is set.
+	RawLine string
+}
+
+// Merchant returns the NormalizedMerchant when there is one, and the MerchantName otherwise.
+func (t *Transaction) Merchant() string {
+	if t.NormalizedMerchant != "" {
+		return t.NormalizedMerchant
+	}
+	return t.MerchantName
+}
+
+// Posted returns the PostDate, or the Date when the statement only prints one.
+func (t *Transaction) Posted() time.Time {
+	if t.PostDate.IsZero() {
+		return t.Date
+	}
+	return t.PostDate
+}
+
+// Values exports an individual Transaction in a CSV-friendly way — this format is derived from the CSV
+// format (including the Y/M/D style) you get when exporting transactions from Chase.
+func (t *Transaction) Values() []string {
+	return t.LocaleValues(US)
+}
+
+// LocaleValues is like Values, but formats the dates and amount for locale.
+func (t *Transaction) LocaleValues(locale Locale) []string {
+	var tType string
+	switch {
+	case t.Amount < 0:
+		tType = "Payment"
+	default:
+		tType = "Sale"
+	}
+	return []string{
+		tType,                          // "Type"
+		t.Date.Format(locale.Date),     // "Trans Date"
+		t.Posted().Format(locale.Date), // "Post Date"
+		t.Merchant(),                   // "Description"
+		locale.Amount(-t.Amount),       // "Amount"
+	}
+}
+
+// ID returns a stable identifier for the transaction, so that importing the same statement twice
+// doesn't duplicate it downstream. That's its Reference when it has one, and otherwise a hash of
+// its date, amount and merchant as printed, which is the same on every run and platform but is
+// shared by two identical transactions on one day.
+func (t *Transaction) ID() string {
+	if t.Reference != "" {
+		return t.Reference
+	}
+	sum := sha1.Sum([]byte(t.Date.Format("20060102") + "|" + t.Amount.String() + "|" + t.MerchantName))
+	return hex.EncodeToString(sum[:])
+}
+
+// Transactions represents a series of transactions, aliased this way for chronological date sorting.
+type Transactions []Transaction
+
+func (t Transactions) Len() int {
+	return len(t)
+}
+
+func (t Transactions) Less(i, j int) bool {
+	if !t[i].Date.Equal(t[j].Date) {
+		return t[j].Date.Before(t[i].Date)
+	}
+	return sameDayLess(&t[i], &t[j])
+}
+
+func (t Transactions) Swap(i, j int) {
+	t[i], t[j] = t[j], t[i]
+}
+
+// Chronological sorts transactions oldest first, the reverse of Transactions' own order.
+type Chronological Transactions
+
+func (t Chronological) Len() int {
+	return len(t)
+}
+
+func (t Chronological) Less(i, j int) bool {
+	if !t[i].Date.Equal(t[j].Date) {
+		return t[i].Date.Before(t[j].Date)
+	}
+	return sameDayLess(&t[i], &t[j])
+}
+
+// sameDayLess orders transactions from the same day by merchant and then amount, so that
+// sorting is repeatable whichever direction the dates go in.
+func sameDayLess(a, b *Transaction) bool {
+	if a.MerchantName != b.MerchantName {
+		return a.MerchantName < b.MerchantName
+	}
+	return a.Amount < b.Amount
+}
+
+func (t Chronological) Swap(i, j int) {
+	t[i], t[j] = t[j], t[i]
+}
+
+// Statement reprents a list of transactions, plus some sum-oriented metadata used
+// for confirming the validity of the parsed transaction amounts.
+type Statement struct {
+	Transactions    Transactions
+	StartingBalance Cents
+	EndingBalance   Cents
+
+	// AccountNumber is the account number exactly as printed, usually masked down to the
+	// last four digits, or empty if it couldn't be found.
+	AccountNumber string
+
+	// Deposit is set for deposit accounts, whose balance goes down as money is spent, rather
+	// than up as it does on a credit card.
+	Deposit bool
+
+	// Currency is the ISO 4217 code of the currency the statement is in.
+	Currency string
+
+	// PeriodStart and PeriodEnd are the statement's opening and closing dates, or zero if
+	// they couldn't be found.
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+
+	// InterestCharged, FeesCharged and CashAdvances are the totals from the account summary.
+	InterestCharged Cents
+	FeesCharged     Cents
+	CashAdvances    Cents
+
+	// PaymentsAndCredits and Purchases are the account summary's subtotals for each side of the
+	// activity, with payments and credits negative like their transactions.
+	PaymentsAndCredits Cents
+	Purchases          Cents
+
+	// subtotals records which of the CashAdvances, PaymentsAndCredits and Purchases subtotals
+	// were found, since a missing one isn't a zero one.
+	subtotals struct {
+		cashAdvances, payments, purchases bool
+	}
+
+	// merged is set on a statement made by Merge, whose unlisted interest and fees are those of
+	// the statements it was made from that had no transactions of their own for them.
+	merged   bool
+	unlisted Cents
+
+	// CreditLimit and AvailableCredit come from a credit card's account summary, and are zero
+	// if they couldn't be found.
+	CreditLimit     Cents
+	AvailableCredit Cents
+
+	// MinimumPaymentDue and PaymentDueDate come from the account summary, and are zero if
+	// they couldn't be found.
+	MinimumPaymentDue Cents
+	PaymentDueDate    time.Time
+
+	// RewardsEarned and RewardsRedeemed are this period's totals from the rewards summary, in
+	// points or dollars of cash back depending on the card, and are zero if there isn't one.
+	RewardsEarned   float64
+	RewardsRedeemed float64
+}
+
+// Headers returns CSV friendly versions of the Transaction-level field names.
+func (s *Statement) Headers() []string {
+	return []string{
+		"Type",
+		"Trans Date",
+		"Post Date",
+		"Description",
+		"Amount",
+	}
+}
+
+// DefaultTolerance is the rounding drift that ParseStatement allows when reconciling.
+const DefaultTolerance Cents = 1
+
+// Reconcile will check the sum of all the statement amounts against the parsed
+// starting and ending balances, requiring them to match to the cent.
+func (s *Statement) Reconcile() (Cents, bool) {
+	return s.ReconcileWithin(0)
+}
+
+// ReconcileWithin is like Reconcile, but is also OK when the sum is within tolerance of
+// the ending balance. The computed total is returned either way, so the discrepancy can be reported.
+//
+// Interest and fees that only appear in the account summary, with no matching Interest or Fee
+// transactions, are added to the total.
+func (s *Statement) ReconcileWithin(tolerance Cents) (Cents, bool) {
+	var tl tally
+	for _, t := range s.Transactions {
+		tl.add(t)
+	}
+	return s.reconcile(tl, tolerance)
+}
+
+// tally is the running sum of a statement's transactions that reconciling needs, so that it can
+// be kept as they're parsed without holding on to them.
+type tally struct {
+	count                int
+	total                Cents
+	sawInterest, sawFees bool
+}
+
+func (tl *tally) add(t Transaction) {
+	tl.count++
+	tl.total += t.Amount
+	tl.sawInterest = tl.sawInterest || t.Kind == Interest
+	tl.sawFees = tl.sawFees || t.Kind == Fee
+}
+
+func (s *Statement) reconcile(tl tally, tolerance Cents) (Cents, bool) {
+	total := s.StartingBalance + s.sign()*tl.total + s.unlistedCharges(tl)
+	return total, (total - s.EndingBalance).Abs() <= tolerance
+}
+
+// unlistedCharges is the interest and fees from the account summary that aren't among the
+// transactions tallied in tl, and so still need adding to their total.
+func (s *Statement) unlistedCharges(tl tally) Cents {
+	if s.merged {
+		return s.unlisted
+	}
+	var charges Cents
+	if !tl.sawInterest {
+		charges += s.InterestCharged
+	}
+	if !tl.sawFees {
+		charges += s.FeesCharged
+	}
+	return charges
+}
+
+// sign is the direction a transaction's Amount moves the statement's balance in.
+func (s *Statement) sign() Cents {
+	if s.Deposit {
+		return -1
+	}
+	return 1
+}
+
+// Suspects returns the transactions most likely to explain why total (as returned by Reconcile)
+// doesn't match the ending balance: those whose removal, or whose sign being flipped, would
+// make the statement balance.
+func (s *Statement) Suspects(total Cents) Transactions {
+	discrepancy := total - s.EndingBalance
+	var suspects Transactions
+	for _, t := range s.Transactions {
+		amt := s.sign() * t.Amount
+		if amt == discrepancy || 2*amt == discrepancy {
+			suspects = append(suspects, t)
+		}
+	}
+	return suspects
+}
+
+// Summary totals a statement's transactions on either side of the Sale/Payment split that
+// Values() makes. Payments is negative, so Net is simply their sum.
+type Summary struct {
+	Sales    Cents
+	Payments Cents
+	Net      Cents
+}
+
+// Summary totals the statement's transactions.
+func (s *Statement) Summary() Summary {
+	var sum Summary
+	for _, t := range s.Transactions {
+		if t.Amount < 0 {
+			sum.Payments += t.Amount
+		} else {
+			sum.Sales += t.Amount
+		}
+	}
+	sum.Net = sum.Sales + sum.Payments
+	return sum
+}
+
+// MonthSummary is the Summary of the transactions made in one calendar month.
+type MonthSummary struct {
+	Month time.Time // the first day of the month
+	Summary
+}
+
+// Monthly totals the statement's transactions by the month of their transaction date, in
+// chronological order. Months without transactions are left out.
+func (s *Statement) Monthly() []MonthSummary {
+	var months []MonthSummary
+	index := map[time.Time]int{}
+	for _, t := range s.Transactions {
+		month := time.Date(t.Date.Year(), t.Date.Month(), 1, 0, 0, 0, 0, t.Date.Location())
+		i, ok := index[month]
+		if !ok {
+			i = len(months)
+			index[month] = i
+			months = append(months, MonthSummary{Month: month})
+		}
+		if t.Amount < 0 {
+			months[i].Payments += t.Amount
+		} else {
+			months[i].Sales += t.Amount
+		}
+		months[i].Net += t.Amount
+	}
+	sort.Slice(months, func(i, j int) bool { return months[i].Month.Before(months[j].Month) })
+	return months
+}
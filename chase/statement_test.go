@@ -0,0 +1,103 @@
+package chase
+
+import (
+	"bytes"
+	"sort"
+	"testing"
+)
+
+func TestSortOrder(t *testing.T) {
+	unsorted := Transactions{
+		{MerchantName: "B", Date: date(2024, 1, 10), Amount: 100},
+		{MerchantName: "A", Date: date(2024, 1, 5), Amount: 200},
+		{MerchantName: "C", Date: date(2024, 1, 20), Amount: 300},
+	}
+	tests := []struct {
+		name  string
+		sort  func(Transactions)
+		order string
+	}{
+		{"desc", func(ts Transactions) { sort.Sort(ts) }, "CBA"},
+		{"asc", func(ts Transactions) { sort.Sort(Chronological(ts)) }, "ABC"},
+	}
+	for _, test := range tests {
+		ts := append(Transactions(nil), unsorted...)
+		test.sort(ts)
+		var got string
+		for _, tr := range ts {
+			got += tr.MerchantName
+		}
+		if got != test.order {
+			t.Errorf("%s: got %s, want %s", test.name, got, test.order)
+		}
+	}
+}
+
+// TestSameDayOrder checks that transactions on the same day come out in the same order however
+// they went in, by merchant and then amount, in either direction.
+func TestSameDayOrder(t *testing.T) {
+	day := date(2024, 1, 10)
+	want := Transactions{
+		{MerchantName: "AMAZON", Date: day, Amount: 500},
+		{MerchantName: "AMAZON", Date: day, Amount: 1500},
+		{MerchantName: "SHELL", Date: day, Amount: 4000},
+		{MerchantName: "STARBUCKS", Date: day, Amount: -450},
+		{MerchantName: "STARBUCKS", Date: day, Amount: 450},
+	}
+	orders := [][]int{{0, 1, 2, 3, 4}, {4, 3, 2, 1, 0}, {2, 0, 4, 1, 3}, {3, 4, 0, 2, 1}}
+	for _, order := range orders {
+		for _, chronological := range []bool{false, true} {
+			ts := make(Transactions, len(order))
+			for i, j := range order {
+				ts[i] = want[j]
+			}
+			if chronological {
+				sort.Sort(Chronological(ts))
+			} else {
+				sort.Sort(ts)
+			}
+			for i := range ts {
+				if ts[i].MerchantName != want[i].MerchantName || ts[i].Amount != want[i].Amount {
+					t.Errorf("from %v (chronological %v): got %s %s at %d, want %s %s", order, chronological,
+						ts[i].MerchantName, ts[i].Amount, i, want[i].MerchantName, want[i].Amount)
+				}
+			}
+		}
+	}
+}
+
+// TestMergeReconciles checks that consecutive statements that each reconcile still do once
+// merged, whether their interest is only in the account summary or also listed as a transaction.
+func TestMergeReconciles(t *testing.T) {
+	listed := readFixture(t, "february.txt")
+	unlisted := bytes.Replace(listed, []byte("INTEREST CHARGED\n02/02 PURCHASE INTEREST CHARGE 5.00\n"), nil, 1)
+	tests := []struct {
+		name     string
+		february []byte
+	}{
+		{"summary-only interest", unlisted},
+		{"interest transaction", listed},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			january, _, err := ParseStatement(readFixture(t, "january.txt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			february, _, err := ParseStatement(test.february)
+			if err != nil {
+				t.Fatal(err)
+			}
+			merged := Merge(DedupeExact, february, january)
+			if merged.StartingBalance != 100000 || merged.EndingBalance != 6710 {
+				t.Errorf("got balances %s and %s, want 1000.00 and 67.10", merged.StartingBalance, merged.EndingBalance)
+			}
+			if merged.InterestCharged != 1500 {
+				t.Errorf("got interest %s, want 15.00", merged.InterestCharged)
+			}
+			if total, ok := merged.Reconcile(); !ok {
+				t.Errorf("doesn't reconcile: total %s, New Balance %s", total, merged.EndingBalance)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package chase
+
+import (
+	"fmt"
+	"sort"
+)
+
+// maxGap is the longest stretch between consecutive transactions that Validate takes as normal
+// for a monthly statement.
+const maxGap = 31
+
+// Validate checks the statement for anything that suggests it was misread, returning every
+// finding rather than stopping at the first: a failed reconciliation (as a *ReconcileError,
+// within DefaultTolerance), transactions dated well outside the statement period, zero amounts,
+// payments, purchases and cash advances that don't add up to the account summary's subtotals,
+// and suspiciously long gaps between transactions.
+func (s *Statement) Validate() []error {
+	var errs []error
+	if total, ok := s.ReconcileWithin(DefaultTolerance); !ok {
+		errs = append(errs, &ReconcileError{Actual: total, Expected: s.EndingBalance})
+	}
+	for i := range s.Transactions {
+		t := &s.Transactions[i]
+		// Purchases can post a while after they're made, so dates up to a month before the
+		// period are allowed.
+		if !s.PeriodStart.IsZero() && (t.Date.Before(s.PeriodStart.AddDate(0, -1, 0)) || t.Date.After(s.PeriodEnd)) {
+			errs = append(errs, fmt.Errorf("\"%s\" is dated %s, outside the statement period %s - %s", t.MerchantName,
+				t.Date.Format("01/02/2006"), s.PeriodStart.Format("01/02/2006"), s.PeriodEnd.Format("01/02/2006")))
+		}
+		if t.Amount == 0 {
+			errs = append(errs, fmt.Errorf("\"%s\" on %s has a zero amount", t.MerchantName, t.Date.Format("01/02/2006")))
+		}
+	}
+	// Checking statements have no such subtotals, and a credit card statement's are only checked
+	// when they were found.
+	if !s.Deposit {
+		var payments, purchases, advances Cents
+		for _, t := range s.Transactions {
+			switch {
+			case t.Amount < 0:
+				payments += t.Amount
+			case t.Kind == CashAdvance:
+				advances += t.Amount
+			// Foreign transaction fees count as purchases, other fees and interest don't.
+			case t.Kind == Sale || t.ForeignFee:
+				purchases += t.Amount
+			}
+		}
+		if s.subtotals.payments && payments != s.PaymentsAndCredits {
+			errs = append(errs, fmt.Errorf("payments and credits total %s, but the account summary shows %s", payments, s.PaymentsAndCredits))
+		}
+		if s.subtotals.purchases && purchases != s.Purchases {
+			errs = append(errs, fmt.Errorf("purchases total %s, but the account summary shows %s", purchases, s.Purchases))
+		}
+		if s.subtotals.cashAdvances && advances != s.CashAdvances {
+			errs = append(errs, fmt.Errorf("cash advances total %s, but the account summary shows %s", advances, s.CashAdvances))
+		}
+	}
+	sorted := make(Chronological, len(s.Transactions))
+	copy(sorted, s.Transactions)
+	sort.Sort(sorted)
+	for i := 1; i < len(sorted); i++ {
+		if days := sorted[i].Date.Sub(sorted[i-1].Date).Hours() / 24; days > maxGap {
+			errs = append(errs, fmt.Errorf("no transactions for %.0f days between %s and %s", days,
+				sorted[i-1].Date.Format("01/02/2006"), sorted[i].Date.Format("01/02/2006")))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,65 @@
+package chase
+
+import "fmt"
+
+// WarningCode identifies the kind of problem a Warning is about, for callers that treat some
+// differently from others.
+type WarningCode string
+
+const (
+	WarnBadMatch         WarningCode = "bad-match"         // a Transaction match without enough submatches
+	WarnBadTransaction   WarningCode = "bad-transaction"   // a transaction line whose date or amount couldn't be read
+	WarnUncountedLines   WarningCode = "uncounted-lines"   // more dated lines than transactions found
+	WarnMissingBalance   WarningCode = "missing-balance"   // a balance that couldn't be found or read
+	WarnMultipleBalances WarningCode = "multiple-balances" // several differing New Balance lines
+	WarnGuessedYear      WarningCode = "guessed-year"      // FallbackYear was used
+	WarnSwappedDate      WarningCode = "swapped-date"      // a date read as day/month
+	WarnNewAccount       WarningCode = "new-account"       // a missing Previous Balance taken as zero
+)
+
+// Warning is a problem the parser worked around rather than stopping at. Warnings are errors,
+// so that they can be returned in Errors too.
+type Warning struct {
+	Code    WarningCode
+	Message string
+
+	// Line is the statement line the problem is on, if it's down to a single line.
+	Line string
+}
+
+func (w Warning) Error() string {
+	return w.Message
+}
+
+// warn records a Warning. Parsing through ParseWarnings collects it, and otherwise it's logged
+// to Log.
+func (p *Parser) warn(code WarningCode, line string, format string, v ...interface{}) {
+	w := Warning{Code: code, Message: fmt.Sprintf(format, v...), Line: line}
+	if p.warnings != nil {
+		*p.warnings = append(*p.warnings, w)
+	} else if p.Log != nil {
+		p.Log.Printf("warning: %s", w.Message)
+	}
+}
+
+// ParseWarnings is like Parse, but returns the guesses Parse would log to Log as Warnings,
+// along with the problems it would return in Errors, for callers that would rather present
+// them their own way than have them logged. The error is then only a failed reconciliation,
+// or in strict mode the first problem found.
+func (p *Parser) ParseWarnings(body []byte) (*Statement, []Warning, error) {
+	var warnings []Warning
+	p.warnings = &warnings
+	defer func() { p.warnings = nil }()
+	statement, err := p.Parse(body)
+	if errs, ok := err.(Errors); ok {
+		err = nil
+		for _, e := range errs {
+			if w, ok := e.(Warning); ok {
+				warnings = append(warnings, w)
+			} else {
+				err = e
+			}
+		}
+	}
+	return statement, warnings, err
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/saranrapjs/chase-the-devil/chase"
+)
+
+// compareRows are the transactions of -compare's export, and compareDiffers is set once the
+// statement has been written if any transactions are in only one of the two.
+var (
+	compareRows    []exportRow
+	compareDiffers bool
+)
+
+// exportRow is a transaction read from one of Chase's own CSV exports.
+type exportRow struct {
+	date        time.Time
+	description string
+	amount      chase.Cents // with Values()' signs, as the export has them
+}
+
+// key is what a transaction is matched between the statement and the export on. Descriptions
+// are worded differently in the two, so only the date and amount are compared.
+func (r exportRow) key() string {
+	return r.date.Format("2006-01-02") + "|" + r.amount.String()
+}
+
+func (r exportRow) String() string {
+	return fmt.Sprintf("%s %s %s", r.date.Format(chase.US.Date), r.description, r.amount)
+}
+
+// readExport reads the transactions of the Chase CSV export at path, finding its columns by
+// the headers of Statement.Headers(). Older exports name the date column "Transaction Date".
+func readExport(path string) ([]exportRow, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%s: no header row", path)
+	}
+	cols := map[string]int{}
+	for i, h := range records[0] {
+		cols[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	if i, ok := cols["transaction date"]; ok {
+		cols["trans date"] = i
+	}
+	for _, h := range []string{"trans date", "description", "amount"} {
+		if _, ok := cols[h]; !ok {
+			return nil, fmt.Errorf("%s: no %q column", path, h)
+		}
+	}
+	var rows []exportRow
+	for n, rec := range records[1:] {
+		if len(rec) <= cols["trans date"] || len(rec) <= cols["description"] || len(rec) <= cols["amount"] {
+			return nil, fmt.Errorf("%s: line %d is too short", path, n+2)
+		}
+		date, err := time.ParseInLocation(chase.US.Date, rec[cols["trans date"]], location)
+		if err != nil {
+			return nil, fmt.Errorf("%s: line %d: %v", path, n+2, err)
+		}
+		amount, err := chase.ParseCents(rec[cols["amount"]])
+		if err != nil {
+			return nil, fmt.Errorf("%s: line %d: %v", path, n+2, err)
+		}
+		rows = append(rows, exportRow{date, rec[cols["description"]], amount})
+	}
+	return rows, nil
+}
+
+// writeCompare writes -compare's report: each transaction found in only one of the statement
+// and the export read into compareRows.
+func writeCompare(w io.Writer, s *chase.Statement) error {
+	inExport := map[string][]int{}
+	for i, r := range compareRows {
+		inExport[r.key()] = append(inExport[r.key()], i)
+	}
+	bw := bufio.NewWriter(w)
+	matched := make([]bool, len(compareRows))
+	for _, t := range s.Transactions {
+		row := exportRow{t.Date, t.Merchant(), -t.Amount}
+		if rows := inExport[row.key()]; len(rows) > 0 {
+			matched[rows[0]] = true
+			inExport[row.key()] = rows[1:]
+			continue
+		}
+		compareDiffers = true
+		fmt.Fprintf(bw, "only in the statement: %s\n", row)
+	}
+	n := 0
+	for i, r := range compareRows {
+		if matched[i] {
+			n++
+			continue
+		}
+		compareDiffers = true
+		fmt.Fprintf(bw, "only in the export: %s\n", r)
+	}
+	verbosef("%d transactions match the export\n", n)
+	return bw.Flush()
+}
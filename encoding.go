@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"unicode/utf8"
+)
+
+// encodings are the -encoding values toUTF8 understands.
+var encodings = map[string]bool{"auto": true, "utf-8": true, "latin1": true}
+
+// toUTF8 transcodes statement text in encoding to UTF-8, so merchant names with accents come out
+// intact in the CSV. "latin1" decodes every byte as ISO 8859-1, and "utf-8" replaces any invalid
+// sequences with U+FFFD. "auto" keeps valid UTF-8, and decodes each byte that isn't part of a
+// valid sequence as Latin-1, which is what pdftotext falls back to for some fonts.
+func toUTF8(body []byte, encoding string) []byte {
+	switch encoding {
+	case "latin1":
+		out := make([]byte, 0, len(body))
+		for _, b := range body {
+			out = utf8.AppendRune(out, rune(b))
+		}
+		return out
+	case "utf-8":
+		return bytes.ToValidUTF8(body, []byte("\uFFFD"))
+	}
+	if utf8.Valid(body) {
+		return body
+	}
+	out := make([]byte, 0, len(body))
+	for len(body) > 0 {
+		r, size := utf8.DecodeRune(body)
+		if r == utf8.RuneError && size == 1 {
+			r = rune(body[0])
+		}
+		out = utf8.AppendRune(out, r)
+		body = body[size:]
+	}
+	return out
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/saranrapjs/chase-the-devil/chase"
+)
+
+// Exit codes, so that scripts can tell the ways a conversion can fail apart. The flag package
+// exits with 2 itself when the command line can't be parsed.
+const (
+	exitOK        = 0
+	exitUsage     = 1 // a bad flag value, rules file or layout file
+	exitReconcile = 3 // the transactions didn't add up to the New Balance, or -compare's export
+	exitParse     = 4 // the statement text couldn't be parsed
+	exitIO        = 5 // pdftotext failed, or the input or output couldn't be read or written
+	exitWarning   = 6 // there were warnings, and -fail-on-warning was given
+)
+
+const exitCodesHelp = `
+Exit codes:
+  0  success
+  1  bad usage: an unknown flag value, or an unreadable rules or layout file
+  2  the command line couldn't be parsed
+  3  a statement didn't reconcile, or with -compare, didn't match the export
+  4  a statement couldn't be parsed
+  5  pdftotext failed, or reading the input or writing the output failed
+  6  with -fail-on-warning, something was warned about
+When several statements are converted, the code is that of the first one to fail.
+`
+
+func init() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage of %s:\n", os.Args[0])
+		flag.PrintDefaults()
+		fmt.Fprint(flag.CommandLine.Output(), exitCodesHelp)
+	}
+}
+
+// ioError marks an error reading a statement, to be reported with exitIO.
+type ioError struct {
+	err error
+}
+
+func (e ioError) Error() string {
+	return e.err.Error()
+}
+
+// exitCode picks the exit code that reports err.
+func exitCode(err error) int {
+	switch err.(type) {
+	case nil:
+		return exitOK
+	case *chase.ReconcileError:
+		return exitReconcile
+	case ioError:
+		return exitIO
+	}
+	return exitParse
+}
+
+// warningExit returns code, unless that's success and -fail-on-warning turns the warnings
+// there have been into a failure.
+func warningExit(code int) int {
+	if code == exitOK && *failOnWarning && warningCount.Load() > 0 {
+		return exitWarning
+	}
+	return code
+}
+
+// fatal logs v and exits with code.
+func fatal(code int, v ...interface{}) {
+	log.Print(v...)
+	os.Exit(code)
+}
+
+// fatalf logs a formatted message and exits with code.
+func fatalf(code int, format string, v ...interface{}) {
+	log.Printf(format, v...)
+	os.Exit(code)
+}
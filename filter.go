@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/saranrapjs/chase-the-devil/chase"
+)
+
+// filter reports whether a transaction should be written out.
+type filter func(*chase.Transaction) bool
+
+// buildFilters turns the filtering flags into the list of filters every written transaction
+// has to pass. Filters only affect what's written: statements are reconciled beforehand,
+// against all of their transactions.
+func buildFilters() ([]filter, error) {
+	var filters []filter
+	if *since != "" {
+		d, err := time.ParseInLocation("2006-01-02", *since, location)
+		if err != nil {
+			return nil, fmt.Errorf("bad -since date: %v", err)
+		}
+		filters = append(filters, func(t *chase.Transaction) bool {
+			return !t.Date.Before(d)
+		})
+	}
+	if *until != "" {
+		d, err := time.ParseInLocation("2006-01-02", *until, location)
+		if err != nil {
+			return nil, fmt.Errorf("bad -until date: %v", err)
+		}
+		filters = append(filters, func(t *chase.Transaction) bool {
+			return !t.Date.After(d)
+		})
+	}
+	// Amounts are signed, with payments and credits negative, so the thresholds apply to
+	// their magnitude: -min-amount 100 keeps a $500 payment.
+	if *minAmount > 0 {
+		min := *minAmount
+		filters = append(filters, func(t *chase.Transaction) bool {
+			return t.Amount.Abs().Float() >= min
+		})
+	}
+	if *maxAmount > 0 {
+		max := *maxAmount
+		filters = append(filters, func(t *chase.Transaction) bool {
+			return t.Amount.Abs().Float() <= max
+		})
+	}
+	// Charges are positive on the statement and written out negated, so that's the sign split
+	// here, with zero amounts counted as neither.
+	if *onlyPayments && *onlyPurchases {
+		return nil, fmt.Errorf("-only-payments and -only-purchases can't be used together")
+	}
+	if *onlyPayments {
+		filters = append(filters, func(t *chase.Transaction) bool {
+			return t.Amount < 0
+		})
+	}
+	if *onlyPurchases {
+		filters = append(filters, func(t *chase.Transaction) bool {
+			return t.Amount > 0
+		})
+	}
+	if len(merchantContains) > 0 {
+		subs := make([]string, len(merchantContains))
+		for i, sub := range merchantContains {
+			subs[i] = strings.ToLower(sub)
+		}
+		filters = append(filters, func(t *chase.Transaction) bool {
+			name := strings.ToLower(t.MerchantName)
+			for _, sub := range subs {
+				if strings.Contains(name, sub) {
+					return true
+				}
+			}
+			return false
+		})
+	}
+	if *merchantRegex != "" {
+		re, err := regexp.Compile(*merchantRegex)
+		if err != nil {
+			return nil, fmt.Errorf("bad -merchant-regex: %v", err)
+		}
+		filters = append(filters, func(t *chase.Transaction) bool {
+			return re.MatchString(t.MerchantName)
+		})
+	}
+	return filters, nil
+}
+
+// stringList is a flag.Value collecting every use of a repeatable flag.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+// filterTransactions returns the transactions that pass every filter.
+func filterTransactions(ts chase.Transactions, filters []filter) chase.Transactions {
+	if len(filters) == 0 {
+		return ts
+	}
+	var kept chase.Transactions
+next:
+	for _, t := range ts {
+		for _, f := range filters {
+			if !f(&t) {
+				continue next
+			}
+		}
+		kept = append(kept, t)
+	}
+	return kept
+}
@@ -0,0 +1,3 @@
+module github.com/saranrapjs/chase-the-devil
+
+go 1.23
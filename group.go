@@ -0,0 +1,47 @@
+package main
+
+import (
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/saranrapjs/chase-the-devil/chase"
+)
+
+// merchantTotal is one row of -group-by merchant's output.
+type merchantTotal struct {
+	merchant string
+	count    int
+	total    chase.Cents
+}
+
+// writeGroupByMerchant writes -group-by merchant's CSV: for each merchant, how many
+// transactions it had and their total, largest first. Totals keep the statement's signs, as in
+// -summary, so spending sorts ahead of payments and refunds.
+func writeGroupByMerchant(w io.Writer, s *chase.Statement) error {
+	var totals []*merchantTotal
+	index := map[string]*merchantTotal{}
+	for _, t := range s.Transactions {
+		m := index[t.Merchant()]
+		if m == nil {
+			m = &merchantTotal{merchant: t.Merchant()}
+			index[m.merchant] = m
+			totals = append(totals, m)
+		}
+		m.count++
+		m.total += t.Amount
+	}
+	sort.SliceStable(totals, func(i, j int) bool { return totals[i].total > totals[j].total })
+
+	writer := newCSVWriter(w)
+	if err := writer.Write([]string{"Merchant", "Count", "Total"}); err != nil {
+		return err
+	}
+	for _, m := range totals {
+		if err := writer.Write([]string{m.merchant, strconv.Itoa(m.count), locale.Amount(m.total)}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/saranrapjs/chase-the-devil/chase"
+)
+
+type jsonTransaction struct {
+	ID        string  `json:"id"`
+	Amount    float64 `json:"amount"`
+	Merchant  string  `json:"merchant"`
+	Date      string  `json:"date"`
+	PostDate  string  `json:"postDate,omitempty"`
+	Kind      string  `json:"kind"`
+	Reference string  `json:"reference,omitempty"`
+
+	NormalizedMerchant string `json:"normalizedMerchant,omitempty"`
+	Category           string `json:"category,omitempty"`
+	Source             string `json:"source,omitempty"`
+
+	OriginalAmount   float64 `json:"originalAmount,omitempty"`
+	OriginalCurrency string  `json:"originalCurrency,omitempty"`
+	ForeignFee       bool    `json:"foreignFee,omitempty"`
+
+	RawLine string `json:"rawLine,omitempty"`
+}
+
+type jsonStatement struct {
+	AccountNumber      string            `json:"accountNumber,omitempty"`
+	Currency           string            `json:"currency"`
+	StartingBalance    float64           `json:"startingBalance"`
+	EndingBalance      float64           `json:"endingBalance"`
+	InterestCharged    float64           `json:"interestCharged"`
+	FeesCharged        float64           `json:"feesCharged"`
+	CashAdvances       float64           `json:"cashAdvances"`
+	PaymentsAndCredits float64           `json:"paymentsAndCredits"`
+	Purchases          float64           `json:"purchases"`
+	PeriodStart        string            `json:"periodStart,omitempty"`
+	PeriodEnd          string            `json:"periodEnd,omitempty"`
+	CreditLimit        float64           `json:"creditLimit,omitempty"`
+	AvailableCredit    float64           `json:"availableCredit,omitempty"`
+	MinimumPaymentDue  float64           `json:"minimumPaymentDue,omitempty"`
+	PaymentDueDate     string            `json:"paymentDueDate,omitempty"`
+	RewardsEarned      float64           `json:"rewardsEarned,omitempty"`
+	RewardsRedeemed    float64           `json:"rewardsRedeemed,omitempty"`
+	Transactions       []jsonTransaction `json:"transactions"`
+}
+
+// writeJSON encodes a Statement as a single JSON object, with its transactions nested under
+// "transactions". Dates are written as plain 2006-01-02 calendar dates so they round-trip cleanly.
+func writeJSON(w io.Writer, s *chase.Statement) error {
+	out := jsonStatement{
+		AccountNumber:      s.AccountNumber,
+		Currency:           s.Currency,
+		StartingBalance:    s.StartingBalance.Float(),
+		EndingBalance:      s.EndingBalance.Float(),
+		InterestCharged:    s.InterestCharged.Float(),
+		FeesCharged:        s.FeesCharged.Float(),
+		CashAdvances:       s.CashAdvances.Float(),
+		PaymentsAndCredits: s.PaymentsAndCredits.Float(),
+		Purchases:          s.Purchases.Float(),
+		PeriodStart:        jsonDate(s.PeriodStart),
+		PeriodEnd:          jsonDate(s.PeriodEnd),
+		CreditLimit:        s.CreditLimit.Float(),
+		AvailableCredit:    s.AvailableCredit.Float(),
+		MinimumPaymentDue:  s.MinimumPaymentDue.Float(),
+		PaymentDueDate:     jsonDate(s.PaymentDueDate),
+		RewardsEarned:      s.RewardsEarned,
+		RewardsRedeemed:    s.RewardsRedeemed,
+		Transactions:       make([]jsonTransaction, 0, len(s.Transactions)),
+	}
+	for i := range s.Transactions {
+		out.Transactions = append(out.Transactions, newJSONTransaction(&s.Transactions[i]))
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// writeJSONL encodes a Statement as JSON Lines: one compact transaction object per line, with no
+// enclosing array, so the output can be streamed and appended to. The balances don't fit a
+// per-transaction format, so they're logged to stderr instead, unless -q is given.
+func writeJSONL(w io.Writer, s *chase.Statement) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for i := range s.Transactions {
+		if err := enc.Encode(newJSONTransaction(&s.Transactions[i])); err != nil {
+			return err
+		}
+	}
+	if !*quiet {
+		log.Printf("starting balance %s, ending balance %s\n", s.StartingBalance, s.EndingBalance)
+	}
+	return bw.Flush()
+}
+
+// newJSONTransaction converts t for JSON output, where its Source is only included with
+// -with-source, as in CSV.
+func newJSONTransaction(t *chase.Transaction) jsonTransaction {
+	jt := jsonTransaction{
+		ID:        t.ID(),
+		Amount:    t.Amount.Float(),
+		Merchant:  t.MerchantName,
+		Date:      jsonDate(t.Date),
+		PostDate:  jsonDate(t.PostDate),
+		Kind:      t.Kind.String(),
+		Reference: t.Reference,
+
+		NormalizedMerchant: t.NormalizedMerchant,
+		Category:           t.Category,
+		Source:             t.Source,
+
+		OriginalAmount:   t.OriginalAmount.Float(),
+		OriginalCurrency: t.OriginalCurrency,
+		ForeignFee:       t.ForeignFee,
+
+		RawLine: t.RawLine,
+	}
+	if !*withSource {
+		jt.Source = ""
+	}
+	return jt
+}
+
+// jsonDate formats d as a calendar date, or returns "" when it's unset so omitempty drops it.
+func jsonDate(d time.Time) string {
+	if d.IsZero() {
+		return ""
+	}
+	return d.Format("2006-01-02")
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/saranrapjs/chase-the-devil/chase"
+)
+
+// layoutSpec is the JSON form of a chase.Layout. Patterns left empty are taken from the
+// layout selected with -type.
+type layoutSpec struct {
+	Transaction     string `json:"transaction"`
+	PreviousBalance string `json:"previousBalance"`
+	NewBalance      string `json:"newBalance"`
+	Year            string `json:"year"`
+	Start           string `json:"start"`
+	End             string `json:"end"`
+	Header          string `json:"header"`
+	Deposit         *bool  `json:"deposit"`
+}
+
+// loadLayout reads a layout from the JSON file at path, filling in anything it leaves out from base.
+func loadLayout(path string, base *chase.Layout) (*chase.Layout, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var spec layoutSpec
+	if err := json.NewDecoder(f).Decode(&spec); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	layout := *base
+	// Patterns that capture something need at least as many submatches as they're read by.
+	for _, p := range []struct {
+		name       string
+		pattern    string
+		re         **regexp.Regexp
+		submatches int
+		needs      string
+	}{
+		{"transaction", spec.Transaction, &layout.Transaction, 4, "month, day, description and amount submatches"},
+		{"previousBalance", spec.PreviousBalance, &layout.PreviousBalance, 1, "a submatch for the amount"},
+		{"newBalance", spec.NewBalance, &layout.NewBalance, 1, "a submatch for the amount"},
+		{"year", spec.Year, &layout.Year, 1, "a submatch for the year"},
+		{"start", spec.Start, &layout.Start, 0, ""},
+		{"end", spec.End, &layout.End, 0, ""},
+		{"header", spec.Header, &layout.Header, 0, ""},
+	} {
+		if p.pattern == "" {
+			continue
+		}
+		re, err := regexp.Compile(p.pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s: bad %s pattern: %v", path, p.name, err)
+		}
+		if re.NumSubexp() < p.submatches {
+			return nil, fmt.Errorf("%s: the %s pattern needs %s", path, p.name, p.needs)
+		}
+		*p.re = re
+	}
+	if spec.Deposit != nil {
+		layout.Deposit = *spec.Deposit
+	}
+	return &layout, nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/saranrapjs/chase-the-devil/chase"
+)
+
+const (
+	ledgerLiability = "Liabilities:Chase"
+	ledgerAsset     = "Assets:Chase"
+	ledgerExpense   = "Expenses:Unknown"
+	ledgerOpening   = "Equity:Opening-Balances"
+)
+
+// ledgerAccount returns the account the statement's transactions are posted against.
+func ledgerAccount(s *chase.Statement) string {
+	if s.Deposit {
+		return ledgerAsset
+	}
+	return ledgerLiability
+}
+
+// ledgerBalance returns one of the statement's balances as the amount of its ledgerAccount,
+// which is negative for money owed on a card. Transactions are posted as -Amount either way,
+// since spending lowers an asset and raises a liability.
+func ledgerBalance(s *chase.Statement, balance chase.Cents) chase.Cents {
+	if s.Deposit {
+		return balance
+	}
+	return -balance
+}
+
+// statementSpan returns the earliest and latest transaction dates in a Statement.
+func statementSpan(s *chase.Statement) (start, end time.Time) {
+	for _, t := range s.Transactions {
+		if start.IsZero() || t.Date.Before(start) {
+			start = t.Date
+		}
+		if t.Date.After(end) {
+			end = t.Date
+		}
+	}
+	return start, end
+}
+
+func formatAmount(amt chase.Cents) string {
+	return amt.String()
+}
+
+// ledgerAmount formats amt in Ledger's style, using "$" for dollars and a trailing commodity
+// code for anything else.
+func ledgerAmount(amt chase.Cents, currency string) string {
+	if currency == "USD" {
+		return "$" + formatAmount(amt)
+	}
+	return formatAmount(amt) + " " + currency
+}
+
+// writeLedger encodes a Statement as a Ledger journal: an opening balance, one entry per
+// transaction posted against Liabilities:Chase, or Assets:Chase for a deposit account, and a
+// closing balance assertion. Since the card is a liability, charges are posted to it as negative
+// amounts. Filtered transactions don't add up to the statement's balances, so then only the
+// transactions are written.
+func writeLedger(w io.Writer, s *chase.Statement) error {
+	bw := bufio.NewWriter(w)
+	start, end := statementSpan(s)
+	account := ledgerAccount(s)
+	if !filtered {
+		fmt.Fprintf(bw, "%s Opening Balance\n", start.Format("2006-01-02"))
+		fmt.Fprintf(bw, "    %s  %s\n", account, ledgerAmount(ledgerBalance(s, s.StartingBalance), s.Currency))
+		fmt.Fprintf(bw, "    %s\n\n", ledgerOpening)
+	}
+	for _, t := range s.Transactions {
+		fmt.Fprintf(bw, "%s %s\n", t.Date.Format("2006-01-02"), t.Merchant())
+		fmt.Fprintf(bw, "    %s  %s\n", account, ledgerAmount(-t.Amount, s.Currency))
+		fmt.Fprintf(bw, "    %s\n\n", ledgerExpense)
+	}
+	if !filtered {
+		fmt.Fprintf(bw, "%s Ending Balance\n", end.Format("2006-01-02"))
+		fmt.Fprintf(bw, "    %s  %s = %s\n", account, ledgerAmount(0, s.Currency), ledgerAmount(ledgerBalance(s, s.EndingBalance), s.Currency))
+	}
+	return bw.Flush()
+}
+
+// writeBeancount is the Beancount equivalent of writeLedger. Beancount checks a balance
+// assertion at the start of its day, so the closing assertion is dated the day after the
+// last transaction. The accounts are opened even when filtering leaves out the balances.
+func writeBeancount(w io.Writer, s *chase.Statement) error {
+	bw := bufio.NewWriter(w)
+	start, end := statementSpan(s)
+	account := ledgerAccount(s)
+	fmt.Fprintf(bw, "%s open %s %s\n", start.Format("2006-01-02"), account, s.Currency)
+	fmt.Fprintf(bw, "%s open %s\n", start.Format("2006-01-02"), ledgerExpense)
+	fmt.Fprintf(bw, "%s open %s\n\n", start.Format("2006-01-02"), ledgerOpening)
+	if !filtered {
+		fmt.Fprintf(bw, "%s * \"Opening Balance\"\n", start.Format("2006-01-02"))
+		fmt.Fprintf(bw, "  %s  %s %s\n", account, formatAmount(ledgerBalance(s, s.StartingBalance)), s.Currency)
+		fmt.Fprintf(bw, "  %s\n\n", ledgerOpening)
+	}
+	for _, t := range s.Transactions {
+		fmt.Fprintf(bw, "%s * %s\n", t.Date.Format("2006-01-02"), strconv.Quote(strings.Replace(t.Merchant(), `"`, `'`, -1)))
+		fmt.Fprintf(bw, "  %s  %s %s\n", account, formatAmount(-t.Amount), s.Currency)
+		fmt.Fprintf(bw, "  %s\n\n", ledgerExpense)
+	}
+	if !filtered {
+		fmt.Fprintf(bw, "%s balance %s  %s %s\n", end.AddDate(0, 0, 1).Format("2006-01-02"), account, formatAmount(ledgerBalance(s, s.EndingBalance)), s.Currency)
+	}
+	return bw.Flush()
+}
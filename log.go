@@ -0,0 +1,45 @@
+package main
+
+// The logging helpers below sit on top of log according to -q and -v: by default warnings and
+// errors are printed, -v adds match counts and per-transaction diagnostics, and -q leaves only
+// errors. Fatal errors always go straight through fatal, with one of the exit codes in exit.go.
+// Warnings are counted whether or not they're printed, for -fail-on-warning.
+
+import (
+	"io"
+	l "log"
+	"sync/atomic"
+)
+
+// warningCount is the number of warnings so far, from warnf and from the parser.
+var warningCount atomic.Int64
+
+// parserLog is the Parser.Log warnings are counted through.
+var parserLog = l.New(countingWriter{stderr}, "", l.LstdFlags)
+
+// countingWriter counts each of a logger's lines as a warning on its way to w.
+type countingWriter struct {
+	w io.Writer
+}
+
+func (c countingWriter) Write(p []byte) (int, error) {
+	warningCount.Add(1)
+	return c.w.Write(p)
+}
+
+func errorf(format string, v ...interface{}) {
+	log.Printf(format, v...)
+}
+
+func warnf(format string, v ...interface{}) {
+	warningCount.Add(1)
+	if !*quiet {
+		log.Printf("warning: "+format, v...)
+	}
+}
+
+func verbosef(format string, v ...interface{}) {
+	if *verbose && !*quiet {
+		log.Printf(format, v...)
+	}
+}
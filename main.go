@@ -1,214 +1,1016 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/csv"
-	"errors"
 	"flag"
+	"fmt"
+	"io"
 	l "log"
-	"math"
 	"os"
-	"os/exec"
-	"regexp"
+	"path/filepath"
+	"runtime"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
+
+	"github.com/saranrapjs/chase-the-devil/chase"
 )
 
-// Transaction represents a basic statement transaction, as shown by Chase in their Credit Card PDF statements.
-type Transaction struct {
-	Amount       float64
-	MerchantName string
-	Date         time.Time
-}
+var log = l.New(stderr, "", l.LstdFlags)
 
-var log = l.New(os.Stderr, "", l.LstdFlags)
+var (
+	format        = flag.String("format", "csv", "output format: csv, json, jsonl, ofx, qif, ledger, beancount, xlsx, camt053 or sqlite")
+	output        string
+	dir           = flag.String("dir", "", "convert every *.pdf statement in `directory`, as well as any named on the command line")
+	force         = flag.Bool("force", false, "overwrite the output file if it already exists")
+	dedupeMode    = flag.String("dedupe", "exact", "how to drop transactions repeated across statements: none, exact or fuzzy (ignoring case and punctuation in the merchant)")
+	strict        = flag.Bool("strict", false, "abort on the first malformed transaction or balance instead of skipping it")
+	pdftotext     = flag.String("pdftotext", "", "`path` to the pdftotext binary (defaults to $CHASE_PDFTOTEXT, then the PATH)")
+	textInput     = flag.Bool("text", false, "read the input as already-extracted statement text, skipping pdftotext (implied for \"-\", which reads stdin)")
+	account       = flag.Bool("account", false, "precede CSV output with a \"# Account Number\" comment line")
+	year          = flag.Int("year", 0, "stamp transactions with this `year` instead of the one found in the statement")
+	tolerance     = flag.Int("tolerance", 1, "how many `cents` the parsed total may differ from the New Balance and still reconcile")
+	normalize     = flag.Bool("normalize", false, "clean up merchant names, dropping store numbers and locations and canonicalizing well-known merchants")
+	rulesFile     = flag.String("rules", "", "categorize transactions with the merchant regexp rules in `file` (.json, or CSV of pattern,category), adding a Category column")
+	since         = flag.String("since", "", "only write transactions on or after this `date` (2006-01-02)")
+	until         = flag.String("until", "", "only write transactions on or before this `date` (2006-01-02)")
+	minAmount     = flag.Float64("min-amount", 0, "only write transactions of at least this many `dollars`, by magnitude, so payments and credits are compared as positive amounts")
+	maxAmount     = flag.Float64("max-amount", 0, "only write transactions of at most this many `dollars`, by magnitude (0 means no limit)")
+	onlyPayments  = flag.Bool("only-payments", false, "only write payments and credits, the transactions written as positive amounts")
+	onlyPurchases = flag.Bool("only-purchases", false, "only write purchases and other charges, the transactions written as negative amounts")
+	noReconcile   = flag.Bool("no-reconcile", false, "skip checking that the transactions add up to the New Balance")
+	warnReconcile = flag.Bool("warn-reconcile", false, "only warn when the transactions don't add up to the New Balance, and write the output anyway")
+	quiet         = flag.Bool("q", false, "quiet: only print errors")
+	verbose       = flag.Bool("v", false, "verbose: also print match counts and per-transaction diagnostics")
+	debug         = flag.Bool("debug", false, "print a table of what was parsed from each transaction line to stderr")
+	currency      = flag.String("currency", chase.DefaultCurrency, "ISO 4217 `code` of the currency the statements are in")
+	sortOrder     = flag.String("sort", "desc", "transaction `order`: desc for newest first, as in Chase's own export, or asc for oldest first")
+	summary       = flag.Bool("summary", false, "print totals of sales, payments and the net change to stderr after the output")
+	accountType   = flag.String("profile", "auto", "the bundled statement layout `preset` to parse with: credit (card), checking, or auto to pick whichever finds the most transactions and reconciles")
+	layoutFile    = flag.String("layout", "", "read the statement layout's regexp patterns from a JSON `file`, for statements whose layout has drifted; patterns it leaves out come from -profile")
+	validate      = flag.Bool("validate", false, "only parse and reconcile the statements, printing their totals, without writing any output")
+	keepRaw       = flag.Bool("keep-raw", false, "keep the statement line each transaction was parsed from, for auditing; JSON output includes it as rawLine")
+	balanceRow    = flag.Bool("balance-row", false, "end CSV output with an \"Ending Balance\" row, when the statement reconciles")
+	timeout       = flag.Duration("timeout", 30*time.Second, "give up on pdftotext if it hasn't finished converting a statement after this long (0 means no limit)")
+	stream        = flag.Bool("stream", false, "write each CSV row as soon as it's parsed, in statement order rather than sorted, and only reconcile afterwards; for very long statements")
+	jobs          = flag.Int("jobs", runtime.NumCPU(), "how many statements to convert at once")
+	merchantRegex = flag.String("merchant-regex", "", "only write transactions whose merchant matches this regular `expression`")
+	report        = flag.String("report", "", "instead of converting the statements, write a report in this `format` (only json) of whether each parsed and reconciled")
+	encoding      = flag.String("encoding", "auto", "the `encoding` of the statement text: utf-8, latin1, or auto to keep valid UTF-8 and read anything else as Latin-1")
+	delimiter     = flag.String("delimiter", ",", "the `character` separating CSV fields, e.g. \";\", or \"tab\"")
+	quoteAll      = flag.Bool("quote-all", false, "quote every CSV field, not just those that need it")
+	columnList    = flag.String("columns", "", "comma separated CSV `columns` to write, in order, from: Type, Trans Date (or Date), Post Date, Description (or Payee, Merchant), Amount, Category and Source; each header is written as given")
+	section       = flag.Int("section", 0, "only convert the `n`th statement of those a file holds, counting from 1, rather than all of them")
+	localeName    = flag.String("locale", "us", "how CSV output writes dates and amounts: us (01/02/2006, 1234.56), uk (02/01/2006), de (02.01.2006, 1.234,56), fr (02/01/2006, 1 234,56) or iso (2006-01-02)")
+	dateFormat    = flag.String("date-format", "", "how CSV output writes dates, overriding -locale: iso, us, eu, or a Go time `layout` such as 2006-01-02")
+	roundMode     = flag.String("round-mode", "half-up", "how amounts printed with fractions of a cent are rounded: half-up (away from zero), half-even or truncate")
+	timezone      = flag.String("timezone", "UTC", "the time `zone` statement dates are taken to be in, e.g. America/New_York or Local")
+	password      = flag.String("password", "", "the `password` for encrypted PDFs; CHASE_PDF_PASSWORD or the prompt shown when one is needed keep it out of your shell history")
+	annotate      = flag.Bool("annotate", false, "start CSV output with comment lines saying which files it came from, when, and whether they reconciled")
+	rollup        = flag.String("rollup", "", "also write sales, payments and net change totals for each month as CSV to this file")
+	newAccount    = flag.Bool("new-account", false, "start from a zero balance when the statement has no Previous Balance line, as on a new card's first statement")
+	showVersion   = flag.Bool("version", false, "print the version and exit")
+	groupBy       = flag.String("group-by", "", "instead of the transactions, write CSV totals grouped by this field (only merchant), largest first")
+	expectEnding  = flag.String("expect-ending", "", "fail unless the parsed New Balance is this `balance`, as read off the PDF, to catch the layout matching the wrong figure")
+	splitAccounts = flag.Bool("split-accounts", false, "write each account's transactions to its own file, named after -o with the last four digits of the account number, e.g. out-1234.csv")
+	numberFormat  = flag.String("number-format", "us", "how the statement writes amounts: us (1,234.56), eu (1.234,56), or auto to decide from each amount's separators")
+	failOnWarning = flag.Bool("fail-on-warning", false, "exit with status 6 if anything was warned about, even with -q; see the README for what counts")
+	withSource    = flag.Bool("with-source", false, "add a Source column naming the statement file each transaction came from")
+	redactOutput  = flag.Bool("redact", false, "mask merchant names, references and the account number, keeping amounts and dates, so output can be shared in bug reports")
+	compareFile   = flag.String("compare", "", "instead of converting the statements, list the transactions found in only one of them and this Chase CSV `export`")
+	pdfMode       = flag.String("pdf-mode", "auto", "the pdftotext mode to extract text in: raw, layout, simple (its default), or auto to try raw and fall back to layout when no transactions are found")
+	dbFile        = flag.String("db", "", "with -format sqlite, upsert into the SQLite database at `file` through sqlite3, creating it if need be, rather than writing SQL")
+	sqlite3       = flag.String("sqlite3", "sqlite3", "`path` to the sqlite3 binary -db runs")
+)
 
-// Values exports an individual Transaction in a CSV-friendly way — this format is derived from the CSV
-// format (including the Y/M/D style) you get when exporting transactions from Chase.
-func (t *Transaction) Values() []string {
-	var tType string
-	switch {
-	case t.Amount < 0:
-		tType = "Payment"
-	default:
-		tType = "Sale"
-	}
-	return []string{
-		tType, // "Type"
-		t.Date.Format("01/02/2006"),                    // "Trans Date"
-		t.Date.Format("01/02/2006"),                    // "Post Date"
-		t.MerchantName,                                 // "Description"
-		strconv.FormatFloat(-1.0*t.Amount, 'f', 2, 64), // "Amount"
-	}
-}
+// merchantContains holds each -merchant-contains flag.
+var merchantContains stringList
 
-// Transactions represents a series of transactions, aliased this way for chronological date sorting.
-type Transactions []Transaction
+// comments holds each -comment flag.
+var comments stringList
 
-func (t Transactions) Len() int {
-	return len(t)
+func init() {
+	flag.StringVar(&output, "o", "", "write output to `file` instead of stdout")
+	flag.StringVar(&output, "output", "", "write output to `file` instead of stdout")
+	flag.StringVar(accountType, "type", "auto", "the older name of -profile")
+	flag.Var(&comments, "comment", "start CSV output with a \"# `text`\" line; may be repeated")
+	flag.Var(&merchantContains, "merchant-contains", "only write transactions whose merchant contains `text`, ignoring case; may be repeated to keep any of several merchants")
 }
 
-func (t Transactions) Less(i, j int) bool {
-	return t[j].Date.Before(t[i].Date)
+// writers maps each supported --format value to the function that encodes a Statement in it.
+var writers = map[string]func(io.Writer, *chase.Statement) error{
+	"csv":       writeCSV,
+	"json":      writeJSON,
+	"ofx":       writeOFX,
+	"qif":       writeQIF,
+	"ledger":    writeLedger,
+	"beancount": writeBeancount,
+	"xlsx":      writeXLSX,
+	"camt053":   writeCAMT053,
+	"jsonl":     writeJSONL,
+	"sqlite":    writeSQLite,
 }
 
-func (t Transactions) Swap(i, j int) {
-	t[i], t[j] = t[j], t[i]
+var layouts = map[string]*chase.Layout{
+	"credit":   chase.CreditCard,
+	"checking": chase.Checking,
 }
 
-// Statement reprents a list of transactions, plus some sum-oriented metadata used
-// for confirming the validity of the parsed transaction amounts.
-type Statement struct {
-	Transactions    Transactions
-	StartingBalance float64
-	EndingBalance   float64
+// profiles are the names of the layouts -profile auto picks from, in order of preference.
+var profiles = []string{"credit", "checking"}
+
+// numberFormats are the -number-format values for parsing statement amounts.
+var numberFormats = map[string]chase.NumberFormat{
+	"us":   chase.NumbersUS,
+	"eu":   chase.NumbersEU,
+	"auto": chase.NumbersAuto,
 }
 
-// Headers returns CSV friendly versions of the Transaction-level field names.
-func (s *Statement) Headers() []string {
-	return []string{
-		"Type",
-		"Trans Date",
-		"Post Date",
-		"Description",
-		"Amount",
-	}
+// locales are the -locale values for formatting CSV output.
+var locales = map[string]chase.Locale{
+	"us":  chase.US,
+	"uk":  {Date: "02/01/2006", Decimal: "."},
+	"de":  {Date: "02.01.2006", Decimal: ",", Thousands: "."},
+	"fr":  {Date: "02/01/2006", Decimal: ",", Thousands: " "},
+	"iso": {Date: "2006-01-02", Decimal: "."},
 }
 
-// Reconcile will check the sum of all the statement amounts against the parsed
-// starting and ending balances.
-func (s *Statement) Reconcile() (float64, bool) {
-	var isOK bool
-	total := s.StartingBalance
-	for _, t := range s.Transactions {
-		total += t.Amount
-	}
-	if toFixed(total, 2) == s.EndingBalance {
-		isOK = true
-	}
-	return total, isOK
+// dateFormats are the named -date-format presets; anything else is taken as a time.Format layout.
+var dateFormats = map[string]string{
+	"us":  "01/02/2006",
+	"eu":  "02/01/2006",
+	"iso": "2006-01-02",
+}
+
+var roundModes = map[string]chase.Rounding{
+	"half-up":   chase.RoundHalfUp,
+	"half-even": chase.RoundHalfEven,
+	"truncate":  chase.RoundTruncate,
 }
 
-var findStatements = regexp.MustCompile(`(?m)^([0-9]{0,2})/([0-9]{0,2}) (.*) ([0-9\-\.,]+)`)
+// location is the time zone picked by -timezone.
+var location *time.Location
 
+// locale is the CSV output locale picked by -locale and -date-format.
+var locale chase.Locale
+
+// expectedEnding is the New Balance given by -expect-ending, or nil.
+var expectedEnding *chase.Cents
+
+// layout is the statement layout picked by -profile and -layout.
+var layout *chase.Layout
+
+// profile names the layouts entry that layout is, or is built on, and autoProfile is set when
+// it's only the starting point for picking a layout for each statement, for -profile auto.
 var (
-	findPreviousBalance = regexp.MustCompile(`(?m)^Previous Balance \$([0-9\-\.,]+)`)
-	findNewBalance      = regexp.MustCompile(`(?m)^New Balance \$([0-9\-\.,]+)`)
-	findYear            = regexp.MustCompile(`(?m)^([0-9]{0,4}) Totals Year-to-Date`)
+	profile     string
+	autoProfile bool
 )
 
+var dedupeModes = map[string]chase.Dedupe{
+	"none":  chase.DedupeNone,
+	"exact": chase.DedupeExact,
+	"fuzzy": chase.DedupeFuzzy,
+}
+
 func main() {
 	flag.Parse()
-
-	args := flag.Args()
-	if len(args) < 1 {
-		log.Fatal("missing file path")
+	if *showVersion {
+		fmt.Println(versionString())
+		os.Exit(exitOK)
 	}
 
-	file := args[0]
-
-	body, _ := exec.Command("pdftotext", "-raw", "-nopgbrk", file, "-").Output()
-	var statement Statement
+	write, ok := writers[*format]
+	if !ok {
+		fatalf(exitUsage, "unknown format %q", *format)
+	}
+	if *compareFile != "" {
+		if *groupBy != "" {
+			fatal(exitUsage, "-compare and -group-by can't be used together")
+		}
+		write = writeCompare
+	}
+	if *dbFile != "" {
+		if *format != "sqlite" {
+			fatal(exitUsage, "-db needs -format sqlite")
+		}
+		if output != "" {
+			fatal(exitUsage, "-db and -o can't be used together")
+		}
+	}
+	switch *groupBy {
+	case "":
+	case "merchant":
+		if *format != "csv" {
+			fatal(exitUsage, "-group-by only writes CSV")
+		}
+		write = writeGroupByMerchant
+	default:
+		fatalf(exitUsage, "unknown -group-by field %q", *groupBy)
+	}
 
-	var yearBytes []byte
-	if yb := findYear.FindSubmatch(body); yb != nil {
-		yearBytes = yb[1]
+	files := flag.Args()
+	if *dir != "" {
+		pdfs, err := filepath.Glob(filepath.Join(*dir, "*.pdf"))
+		if err != nil {
+			fatal(exitUsage, err)
+		}
+		files = append(files, pdfs...)
+	}
+	if len(files) < 1 {
+		fatal(exitUsage, "missing file path")
+	}
+	dedupe, ok := dedupeModes[*dedupeMode]
+	if !ok {
+		fatalf(exitUsage, "unknown dedupe mode %q", *dedupeMode)
 	}
 
-	sts := findStatements.FindAllSubmatch(body, -1)
-	log.Printf("Found %d matches\n", len(sts))
-	for i, st := range sts {
-		if len(st) < 4 {
-			log.Fatalf("Bad match for match no %d, aborting\n", i)
-			continue
+	profile = *accountType
+	if profile == "auto" {
+		profile = profiles[0]
+		// A -layout file is built on one profile, so it isn't chosen between.
+		autoProfile = *layoutFile == ""
+	}
+	layout = layouts[profile]
+	if layout == nil {
+		fatalf(exitUsage, "unknown statement profile %q", *accountType)
+	}
+	if *layoutFile != "" {
+		l, err := loadLayout(*layoutFile, layout)
+		if err != nil {
+			fatal(exitUsage, err)
 		}
-		var t Transaction
-		t.MerchantName = string(st[3])
-		amt, err := sanitizeAmount(string(st[4]))
+		layout = l
+	}
+	if *columnList != "" {
+		cols, names, err := parseColumns(*columnList)
 		if err != nil {
-			log.Fatalf("Bad amount parse for \"%s\": %v, aborting\n", t.MerchantName, err)
-			continue
+			fatal(exitUsage, err)
+		}
+		columns, columnNames = cols, names
+	}
+	locale, ok = locales[*localeName]
+	if !ok {
+		fatalf(exitUsage, "unknown locale %q", *localeName)
+	}
+	if *dateFormat != "" {
+		locale.Date = *dateFormat
+		if preset, ok := dateFormats[*dateFormat]; ok {
+			locale.Date = preset
 		}
-		t.Amount = amt
-		d, err := createDate(st[2], st[1], yearBytes)
+	}
+	loc, err := time.LoadLocation(*timezone)
+	if err != nil {
+		fatalf(exitUsage, "bad -timezone: %v", err)
+	}
+	location = loc
+	if *compareFile != "" {
+		rows, err := readExport(*compareFile)
 		if err != nil {
-			log.Fatalf("Bad date parse for \"%s\": %v, aborting\n", t.MerchantName, err)
+			fatal(exitUsage, err)
 		}
-		t.Date = d
-		statement.Transactions = append(statement.Transactions, t)
+		compareRows = rows
 	}
-	if start := findPreviousBalance.FindSubmatch(body); start != nil {
-		amt, err := sanitizeAmount(string(start[1]))
+	if *expectEnding != "" {
+		ending, err := chase.ParseCents(*expectEnding)
 		if err != nil {
-			log.Fatal("error with Previous Balance:", err)
+			fatalf(exitUsage, "bad -expect-ending: %v", err)
+		}
+		// There's only the one balance to expect, so only the one statement to expect it of.
+		if len(files) != 1 {
+			fatal(exitUsage, "-expect-ending checks a single statement, so it needs exactly one file")
+		}
+		expectedEnding = &ending
+	}
+	if _, ok := roundModes[*roundMode]; !ok {
+		fatalf(exitUsage, "unknown rounding mode %q", *roundMode)
+	}
+	if _, ok := pdfModes[*pdfMode]; !ok && *pdfMode != "auto" {
+		fatalf(exitUsage, "unknown pdf mode %q", *pdfMode)
+	}
+	if _, ok := numberFormats[*numberFormat]; !ok {
+		fatalf(exitUsage, "unknown number format %q", *numberFormat)
+	}
+	if !encodings[*encoding] {
+		fatalf(exitUsage, "unknown encoding %q", *encoding)
+	}
+	if d := []rune(*delimiter); *delimiter != "tab" && *delimiter != `\t` &&
+		(len(d) != 1 || d[0] == '"' || d[0] == '\r' || d[0] == '\n') {
+		fatalf(exitUsage, "bad -delimiter %q, it should be a single character", *delimiter)
+	}
+	if *report != "" && *report != "json" {
+		fatalf(exitUsage, "unknown report format %q", *report)
+	}
+	if *sortOrder != "asc" && *sortOrder != "desc" {
+		fatalf(exitUsage, "unknown sort order %q", *sortOrder)
+	}
+	filters, err := buildFilters()
+	if err != nil {
+		fatal(exitUsage, err)
+	}
+	var rules chase.Rules
+	if *rulesFile != "" {
+		r, err := loadRules(*rulesFile)
+		if err != nil {
+			fatal(exitUsage, err)
+		}
+		rules = r
+	}
+
+	if *stream {
+		if *format != "csv" || len(files) != 1 || *account || *rollup != "" || *groupBy != "" || *splitAccounts || *compareFile != "" {
+			fatal(exitUsage, "-stream only writes CSV, without -account, -rollup, -group-by, -split-accounts or -compare, for a single statement")
+		}
+		out, err := openOutput(output, *force)
+		if err != nil {
+			fatal(exitIO, "error opening output: ", err)
+		}
+		err = streamCSV(out, files[0], rules, filters)
+		out.Close()
+		if err != nil {
+			fatalf(exitCode(err), "%s: %v\n", files[0], err)
+		}
+		os.Exit(warningExit(exitOK))
+	}
+
+	// Each file is parsed and reconciled on its own, so one bad statement doesn't stop the
+	// rest from being converted.
+	sources = files
+	// A text file can also hold several statements one after another, which are split apart and
+	// treated the same way.
+	var statements []*chase.Statement
+	code := exitOK
+	attempted, failed := 0, 0
+	converted := convertFiles(files, *jobs)
+	for _, results := range converted {
+		for _, r := range results {
+			attempted++
+			if r.err != nil {
+				errorf("%s: %v\n", r.name, r.err)
+				if code == exitOK {
+					code = exitCode(r.err)
+				}
+				failed++
+				continue
+			}
+			if *validate {
+				total, _ := r.statement.ReconcileWithin(chase.Cents(*tolerance))
+				log.Printf("%s: ok, actual: %s, expected %s\n", r.name, total, r.statement.EndingBalance)
+			}
+			statements = append(statements, r.statement)
 		}
-		statement.StartingBalance = amt
-	} else {
-		log.Fatal("could not find starting balance :( ")
 	}
-	if end := findNewBalance.FindSubmatch(body); end != nil {
-		amt, err := sanitizeAmount(string(end[1]))
+	if *validate {
+		os.Exit(warningExit(code))
+	}
+	if *report != "" {
+		out, err := openOutput(output, *force)
 		if err != nil {
-			log.Fatal("error with New Balance:", err)
+			fatal(exitIO, "error opening output: ", err)
+		}
+		if err := writeReport(out, converted); err != nil {
+			fatal(exitIO, "error writing output: ", err)
+		}
+		out.Close()
+		os.Exit(warningExit(code))
+	}
+	if len(statements) == 0 {
+		fatal(code, "no statements could be converted, aborting")
+	}
+	for _, s := range statements {
+		if *normalize {
+			s.Normalize()
+		}
+		if rules != nil {
+			s.Categorize(rules)
+		}
+	}
+
+	if *splitAccounts {
+		if output == "" {
+			fatal(exitUsage, "-split-accounts needs -o to name the files it writes")
+		}
+		for i, acct := range byAccount(statements) {
+			// Redacted output is numbered rather than named for the account.
+			number := acct[0].AccountNumber
+			if *redactOutput {
+				number = ""
+			}
+			rollupPath := *rollup
+			if rollupPath != "" {
+				rollupPath = accountPath(rollupPath, number, i)
+			}
+			writeStatements(accountPath(output, number, i), rollupPath, acct, write, dedupe, filters)
 		}
-		statement.EndingBalance = amt
 	} else {
-		log.Fatal("could not find ending balance :( ")
+		for _, s := range statements[1:] {
+			if s.Deposit != statements[0].Deposit {
+				fatal(exitUsage, "the statements cover both credit card and checking accounts, which can't be merged; use -split-accounts to write each account to its own file")
+			}
+		}
+		writeStatements(output, *rollup, statements, write, dedupe, filters)
 	}
-	sort.Sort(statement.Transactions)
-	if val, res := statement.Reconcile(); !res {
-		log.Fatalf("reconciliation doesn't match :(, actual: %v, expected %v", val, statement.EndingBalance)
+	if failed > 0 {
+		fatalf(code, "%d of %d statements failed to convert\n", failed, attempted)
 	}
-	writer := csv.NewWriter(os.Stdout)
-	defer writer.Flush()
-	writer.Write(statement.Headers())
-	for _, tr := range statement.Transactions {
-		writer.Write(tr.Values())
+	if compareDiffers {
+		fatal(exitReconcile, "the statement doesn't match the export")
+	}
+	if code := warningExit(exitOK); code != exitOK {
+		fatalf(code, "%d warning(s), failing as -fail-on-warning asks\n", warningCount.Load())
 	}
 }
 
-func sanitizeAmount(amtString string) (float64, error) {
-	amtString = strings.Replace(amtString, ",", "", -1)
-	num, err := strconv.ParseFloat(amtString, 64)
+// filtered is set when filters may have left some of the statement being written out, so that
+// its balances no longer add up across the transactions that are written.
+var filtered bool
+
+// writeStatements merges statements into one, then filters, sorts and writes it to path (or
+// stdout if it's empty), along with the -summary totals and any -rollup to rollupPath.
+func writeStatements(path, rollupPath string, statements []*chase.Statement, write func(io.Writer, *chase.Statement) error, dedupe chase.Dedupe, filters []filter) {
+	out, err := openOutput(path, *force)
 	if err != nil {
-		return 0.0, err
+		fatal(exitIO, "error opening output: ", err)
+	}
+	// SQLite keeps a row for each statement, and its upserts already dedupe the transactions,
+	// so it's given the statements unmerged.
+	unmerged := *format == "sqlite" && *compareFile == ""
+	statement := statements[0]
+	if len(statements) > 1 {
+		statement = chase.Merge(dedupe, statements...)
+		// Merged statements only reconcile when they're consecutive, so a mismatch points at a
+		// missing month or an undetected duplicate rather than a bad parse.
+		if val, ok := statement.ReconcileWithin(chase.Cents(*tolerance)); !ok && !*noReconcile && !unmerged {
+			warnf("merged statements don't reconcile, actual: %s, expected %s\n", val, statement.EndingBalance)
+		}
+	}
+	reconcileStatus = "yes"
+	if total, ok := statement.ReconcileWithin(chase.Cents(*tolerance)); !ok {
+		reconcileStatus = fmt.Sprintf("no, actual: %s, expected %s", total, statement.EndingBalance)
+	}
+	filtered = len(filters) > 0
+	prepare := func(s *chase.Statement) *chase.Statement {
+		if filtered {
+			kept := *s
+			kept.Transactions = filterTransactions(s.Transactions, filters)
+			s = &kept
+		}
+		if *sortOrder == "asc" {
+			sort.Sort(chase.Chronological(s.Transactions))
+		}
+		// Redacting comes last, so that the filters and accounts work from the real details.
+		if *redactOutput {
+			s = redact(s)
+		}
+		return s
+	}
+	statement = prepare(statement)
+	written := []*chase.Statement{statement}
+	if unmerged && len(statements) > 1 {
+		written = nil
+		for _, s := range statements {
+			written = append(written, prepare(s))
+		}
+	}
+	for _, s := range written {
+		if err := write(out, s); err != nil {
+			fatal(exitIO, "error writing output: ", err)
+		}
+	}
+	out.Close()
+	if rollupPath != "" {
+		f, err := openOutput(rollupPath, *force)
+		if err != nil {
+			fatal(exitIO, err)
+		}
+		if err := writeRollup(f, statement); err != nil {
+			fatal(exitIO, "error writing rollup: ", err)
+		}
+		f.Close()
+	}
+	if *summary {
+		sum := statement.Summary()
+		log.Printf("sales %s, payments %s, net change %s\n", sum.Sales, sum.Payments, sum.Net)
+		// The check is the one reconciling makes, so interest and fees that are only in the
+		// account summary count towards it, and a deposit account's balance goes down by the
+		// net change rather than up.
+		sign := "+"
+		if statement.Deposit {
+			sign = "-"
+		}
+		total, _ := statement.ReconcileWithin(chase.Cents(*tolerance))
+		log.Printf("previous balance %s %s net change, interest and fees = %s, new balance %s\n",
+			statement.StartingBalance, sign, total, statement.EndingBalance)
 	}
-	return toFixed(num, 2), nil
 }
 
-func round(num float64) int {
-	return int(num + math.Copysign(0.5, num))
+// byAccount groups statements by their account number, in the order each account first appears.
+func byAccount(statements []*chase.Statement) [][]*chase.Statement {
+	var groups [][]*chase.Statement
+	index := map[string]int{}
+	for _, s := range statements {
+		i, ok := index[s.AccountNumber]
+		if !ok {
+			i = len(groups)
+			index[s.AccountNumber] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], s)
+	}
+	return groups
+}
+
+// accountPath names the -split-accounts output file for the ith account, by adding the last
+// four digits of its number (or i, counting from 1, if it has none) to path, e.g. out-1234.csv.
+func accountPath(path, number string, i int) string {
+	suffix := strconv.Itoa(i + 1)
+	if number = strings.Replace(number, " ", "", -1); number != "" {
+		suffix = number[max(0, len(number)-4):]
+	}
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "-" + suffix + ext
 }
 
-func toFixed(num float64, precision int) float64 {
-	output := math.Pow(10, float64(precision))
-	return float64(round(num*output)) / output
+// parsed is the outcome of parsing one statement, named for its messages. The statement is
+// kept alongside an error where there is one, for -report.
+type parsed struct {
+	name      string
+	statement *chase.Statement
+	warnings  []error
+	err       error
 }
 
-func createDate(day, month, year []byte) (time.Time, error) {
-	var t time.Time
-	var d, m, y int
-	if day == nil || month == nil || year == nil {
-		return t, errors.New("a piece of the date is missing")
+// convertFiles reads and parses files with up to jobs of them in flight at once, since running
+// pdftotext is most of the work. The results come back in the order of files, with a file that
+// holds several statements giving one result for each.
+func convertFiles(files []string, jobs int) [][]parsed {
+	if jobs < 1 || *debug {
+		// -debug's traces would be interleaved.
+		jobs = 1
 	}
+	results := make([][]parsed, len(files))
+	next := make(chan int)
+	progress := newProgress(len(files))
+	var wg sync.WaitGroup
+	for w := 0; w < jobs && w < len(files); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				results[i] = convertFile(files[i])
+				progress.done(files[i])
+			}
+		}()
+	}
+	for i := range files {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	progress.finish()
+	return results
+}
 
-	d, err := strconv.Atoi(string(day))
+// convertFile reads the statements in file and parses each of them.
+func convertFile(file string) []parsed {
+	body, err := readStatement(file, *textInput)
 	if err != nil {
-		return t, err
+		return []parsed{{name: file, err: ioError{err}}}
+	}
+	// A combined statement covering several accounts is first split by account, since each
+	// needs its own layout.
+	type part struct {
+		layout *chase.Layout
+		body   []byte
+	}
+	var parts []part
+	accounts := chase.SplitAccounts(toUTF8(body, *encoding), accountLayouts()...)
+	for _, acct := range accounts {
+		for _, body := range chase.Split(acct.Body, acct.Layout) {
+			// A statement's layout is picked when it's parsed, unless its account's header
+			// has picked it already.
+			l := acct.Layout
+			if autoProfile && len(accounts) == 1 {
+				l = nil
+			}
+			parts = append(parts, part{l, body})
+		}
+	}
+	if *section > 0 {
+		if *section > len(parts) {
+			return []parsed{{name: file, err: fmt.Errorf("there's no statement %d, only %d", *section, len(parts))}}
+		}
+		parts = parts[*section-1 : *section]
+	}
+	if expectedEnding != nil && len(parts) > 1 {
+		return []parsed{{name: file, err: fmt.Errorf("it holds %d statements, but -expect-ending checks a single one; pick it with -section", len(parts))}}
 	}
+	results := make([]parsed, len(parts))
+	for i, part := range parts {
+		name := file
+		if *section > 0 {
+			name = fmt.Sprintf("%s (statement %d)", file, *section)
+		} else if len(parts) > 1 {
+			name = fmt.Sprintf("%s (statement %d)", file, i+1)
+		}
+		statement, warnings, err := parseStatement(name, file, part.layout, part.body)
+		// The Source is always filled in, for -columns and SQLite, and -with-source only adds
+		// the column.
+		if statement != nil {
+			for i := range statement.Transactions {
+				statement.Transactions[i].Source = filepath.Base(file)
+			}
+		}
+		results[i] = parsed{name, statement, warnings, err}
+	}
+	return results
+}
 
-	m, err = strconv.Atoi(string(month))
-	if err != nil {
-		return t, err
+// accountLayouts returns the layouts a combined statement's accounts may have: the one picked
+// by -profile and -layout, then the other profiles'.
+func accountLayouts() []*chase.Layout {
+	candidates := []*chase.Layout{layout}
+	for _, name := range profiles {
+		if name != profile {
+			candidates = append(candidates, layouts[name])
+		}
 	}
+	return candidates
+}
+
+// detectLayout picks the layout to parse body with for -profile auto: of the profiles whose
+// transactions reconcile, the one that finds the most of them, or failing that, whichever finds
+// the most transactions at all. Ties go to the one first in profiles.
+func detectLayout(name, file string, body []byte) *chase.Layout {
+	best, bestName, bestCount, bestOK := layout, profile, -1, false
+	for _, candidate := range profiles {
+		parser := newParser(file)
+		parser.Layout, parser.Strict, parser.Log, parser.Trace = layouts[candidate], false, nil, nil
+		statement, err := parser.Parse(body)
+		var count int
+		if statement != nil {
+			count = len(statement.Transactions)
+		}
+		ok := count > 0 && reconciled(err)
+		if (ok && !bestOK) || (ok == bestOK && count > bestCount) {
+			best, bestName, bestCount, bestOK = layouts[candidate], candidate, count, ok
+		}
+	}
+	verbosef("%s: parsing with the %s profile\n", name, bestName)
+	return best
+}
 
-	y, err = strconv.Atoi(string(year))
+// parseStatement parses and reconciles the text of a statement read from file with the layout
+// l, logging any warnings under name. A nil l is picked by detectLayout.
+func parseStatement(name, file string, l *chase.Layout, body []byte) (*chase.Statement, []error, error) {
+	if l == nil {
+		l = detectLayout(name, file, body)
+	}
+	parser := newParser(file)
+	parser.Layout = l
+	statement, parseWarnings, err := parser.ParseWarnings(body)
+	if eerr := checkEnding(statement); eerr != nil {
+		return statement, nil, eerr
+	}
+	var warnings []error
+	for _, w := range parseWarnings {
+		warnf("%s: %v\n", name, w)
+		warnings = append(warnings, w)
+	}
+	statement, checked, err := checkParse(name, body, statement, err)
+	warnings = append(warnings, checked...)
 	if err != nil {
-		return t, err
+		return statement, warnings, err
+	}
+	if total, exact := statement.ReconcileWithin(0); !exact && !*noReconcile && reconciled(err) {
+		warnf("%s: only reconciles within -tolerance, actual: %s, expected %s\n", name, total, statement.EndingBalance)
+	}
+	// Reconciliation has been dealt with already, by the reconciliation flags.
+	for _, finding := range statement.Validate() {
+		if _, ok := finding.(*chase.ReconcileError); ok {
+			continue
+		}
+		if *strict {
+			return statement, warnings, finding
+		}
+		warnf("%s: %v\n", name, finding)
+		warnings = append(warnings, finding)
+	}
+	verbosef("Found %d matches in %s\n", len(statement.Transactions), name)
+	return statement, warnings, nil
+}
+
+// checkEnding checks the statement's New Balance against -expect-ending, which catches the
+// balance pattern matching the wrong figure even when the statement happens to reconcile.
+func checkEnding(statement *chase.Statement) error {
+	if expectedEnding == nil || statement == nil || statement.EndingBalance == *expectedEnding {
+		return nil
+	}
+	return fmt.Errorf("the New Balance was parsed as %s but -expect-ending is %s; the layout's balance pattern may be matching the wrong figure",
+		statement.EndingBalance, *expectedEnding)
+}
+
+// newParser returns a Parser configured by the command line flags, for the statement at file.
+func newParser(file string) *chase.Parser {
+	parser := &chase.Parser{
+		Strict:       *strict,
+		Tolerance:    chase.Cents(*tolerance),
+		Year:         *year,
+		Currency:     *currency,
+		Layout:       layout,
+		Rounding:     roundModes[*roundMode],
+		Location:     location,
+		KeepRaw:      *keepRaw,
+		NewAccount:   *newAccount,
+		NumberFormat: numberFormats[*numberFormat],
+	}
+	if !*quiet {
+		parser.Log = parserLog
+	} else if *failOnWarning {
+		parser.Log = l.New(countingWriter{io.Discard}, "", 0)
+	}
+	if *debug {
+		parser.Trace = os.Stderr
+	}
+	if info, err := os.Stat(file); err == nil && file != "-" {
+		parser.FallbackYear = info.ModTime().Year()
+	}
+	return parser
+}
+
+// checkParse reports the outcome of parsing body, logging warnings under name and deciding
+// according to the reconciliation flags whether the statement is good enough to write out. The
+// warnings are returned too, and the statement is returned even when it isn't good enough.
+func checkParse(name string, body []byte, statement *chase.Statement, err error) (*chase.Statement, []error, error) {
+	if err == chase.ErrNoTransactions {
+		errorf("%s: the text extracted from it begins:\n%s\n", name, excerpt(body, 400))
+		return nil, nil, err
+	}
+	// Without -strict, problems other than a failed reconciliation are only warnings.
+	var warnings []error
+	if errs, ok := err.(chase.Errors); ok {
+		err = nil
+		for _, e := range errs {
+			if _, ok := e.(*chase.ReconcileError); ok {
+				err = e
+			} else {
+				warnf("%s: %v\n", name, e)
+				warnings = append(warnings, e)
+			}
+		}
+	}
+	if rerr, ok := err.(*chase.ReconcileError); ok {
+		if *noReconcile {
+			return statement, warnings, nil
+		}
+		for _, t := range statement.Suspects(rerr.Actual) {
+			verbosef("suspect transaction: %s %s %s\n", t.Date.Format("01/02"), t.MerchantName, t.Amount)
+		}
+		if *warnReconcile {
+			warnf("%s: %v\n", name, err)
+			warnings = append(warnings, err)
+			err = nil
+		}
+	}
+	return statement, warnings, err
+}
+
+// excerpt returns up to the first n bytes of body, cut at a line break where possible.
+func excerpt(body []byte, n int) []byte {
+	if len(body) <= n {
+		return body
+	}
+	if i := bytes.LastIndexByte(body[:n], '\n'); i > 0 {
+		return body[:i]
+	}
+	return body[:n]
+}
+
+// openOutput opens path for writing, or returns stdout when path is empty. An existing file
+// is only truncated when force is set.
+func openOutput(path string, force bool) (io.WriteCloser, error) {
+	if path == "" {
+		return os.Stdout, nil
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if force {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	f, err := os.OpenFile(path, flags, 0644)
+	if os.IsExist(err) {
+		return nil, fmt.Errorf("%s already exists, use -force to overwrite it", path)
+	} else if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func writeCSV(w io.Writer, s *chase.Statement) error {
+	if *account && s.AccountNumber != "" {
+		if _, err := fmt.Fprintf(w, "# Account Number: %s\n", s.AccountNumber); err != nil {
+			return err
+		}
+	}
+	if err := writeComments(w); err != nil {
+		return err
+	}
+	writer := newCSVWriter(w)
+	writer.Write(csvHeaders(s))
+	for i := range s.Transactions {
+		writer.Write(csvValues(&s.Transactions[i]))
+	}
+	// s may have been filtered, so whether it reconciled was settled beforehand.
+	if *balanceRow && reconcileStatus == "yes" {
+		writer.Write(csvBalanceRow(s))
 	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// csvColumns maps each name -columns accepts, in lower case, to the field it picks from a row of
+// Values() followed by the transaction's category.
+var csvColumns = map[string]int{
+	"type":        0,
+	"trans date":  1,
+	"date":        1,
+	"post date":   2,
+	"description": 3,
+	"payee":       3,
+	"merchant":    3,
+	"amount":      4,
+	"category":    5,
+	"source":      6,
+}
+
+// columns and columnNames are the fields picked by -columns and the headers to give them, or nil
+// for Chase's own layout.
+var (
+	columns     []int
+	columnNames []string
+)
+
+// parseColumns parses a -columns list, keeping each name as written for its header.
+func parseColumns(spec string) ([]int, []string, error) {
+	var cols []int
+	var names []string
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		col, ok := csvColumns[strings.ToLower(name)]
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown column %q", name)
+		}
+		cols = append(cols, col)
+		names = append(names, name)
+	}
+	return cols, names, nil
+}
+
+// pickColumns picks the -columns fields out of row.
+func pickColumns(row []string) []string {
+	picked := make([]string, len(columns))
+	for i, col := range columns {
+		picked[i] = row[col]
+	}
+	return picked
+}
+
+// sources are the files being converted, and reconcileStatus whether the statement written from
+// them reconciled before any filtering, for -annotate. A streamed statement can't be reconciled
+// until it's been written, so its reconcileStatus is left empty.
+var (
+	sources         []string
+	reconcileStatus string
+)
+
+// writeComments writes the -comment lines, and with -annotate where the statement came from,
+// when it was converted and whether it reconciled.
+func writeComments(w io.Writer) error {
+	lines := append([]string(nil), comments...)
+	if *annotate {
+		lines = append(lines, "Source: "+strings.Join(sources, ", "),
+			"Converted: "+time.Now().Format(time.RFC3339))
+		if reconcileStatus != "" {
+			lines = append(lines, "Reconciled: "+reconcileStatus)
+		}
+	}
+	for _, line := range lines {
+		if _, err := fmt.Fprintf(w, "# %s\n", line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func csvHeaders(s *chase.Statement) []string {
+	if columns != nil {
+		return columnNames
+	}
+	headers := s.Headers()
+	if *rulesFile != "" {
+		headers = append(headers, "Category")
+	}
+	if *withSource {
+		headers = append(headers, "Source")
+	}
+	return headers
+}
+
+func csvValues(t *chase.Transaction) []string {
+	if columns != nil {
+		return pickColumns(append(t.LocaleValues(locale), t.Category, t.Source))
+	}
+	values := t.LocaleValues(locale)
+	if *rulesFile != "" {
+		values = append(values, t.Category)
+	}
+	if *withSource {
+		values = append(values, t.Source)
+	}
+	return values
+}
+
+// csvWriter is the part of csv.Writer that CSV output uses, so -quote-all can stand in for it.
+type csvWriter interface {
+	Write(record []string) error
+	Flush()
+	Error() error
+}
+
+// newCSVWriter returns a writer for CSV output following -delimiter and -quote-all.
+func newCSVWriter(w io.Writer) csvWriter {
+	comma := csvDelimiter()
+	if *quoteAll {
+		return &quotingWriter{w: bufio.NewWriter(w), comma: comma}
+	}
+	writer := csv.NewWriter(w)
+	writer.Comma = comma
+	return writer
+}
+
+// csvDelimiter is the field separator picked by -delimiter, which also takes "tab" or "\t" since
+// a literal tab is awkward to pass on a command line.
+func csvDelimiter() rune {
+	switch *delimiter {
+	case "tab", `\t`:
+		return '\t'
+	}
+	r, _ := utf8.DecodeRuneInString(*delimiter)
+	return r
+}
+
+// quotingWriter writes CSV with every field quoted, which csv.Writer can't be asked to do.
+type quotingWriter struct {
+	w     *bufio.Writer
+	comma rune
+	err   error
+}
 
-	t = time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.Local)
-	return t, nil
+func (q *quotingWriter) Write(record []string) error {
+	for i, field := range record {
+		if i > 0 {
+			q.w.WriteRune(q.comma)
+		}
+		q.w.WriteString(`"` + strings.Replace(field, `"`, `""`, -1) + `"`)
+	}
+	_, err := q.w.WriteString("\n")
+	if err != nil && q.err == nil {
+		q.err = err
+	}
+	return err
+}
+
+func (q *quotingWriter) Flush() {
+	if err := q.w.Flush(); err != nil && q.err == nil {
+		q.err = err
+	}
+}
+
+func (q *quotingWriter) Error() error {
+	return q.err
+}
+
+// csvBalanceRow is the row -balance-row adds after the transactions.
+func csvBalanceRow(s *chase.Statement) []string {
+	// Amounts follow Chase's sign convention, where money owed on a card is negative.
+	balance := -s.EndingBalance
+	if s.Deposit {
+		balance = s.EndingBalance
+	}
+	values := []string{"", "", "", "Ending Balance", locale.Amount(balance)}
+	if columns != nil {
+		return pickColumns(append(values, "", ""))
+	}
+	// The row is padded to the header, as csvValues does the transactions.
+	if *rulesFile != "" {
+		values = append(values, "")
+	}
+	if *withSource {
+		values = append(values, "")
+	}
+	return values
 }
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/saranrapjs/chase-the-devil/chase"
+)
+
+const ofxHeader = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+SECURITY:NONE
+ENCODING:UTF-8
+CHARSET:NONE
+COMPRESSION:NONE
+OLDFILEUID:NONE
+NEWFILEUID:NONE
+
+`
+
+const ofxDate = "20060102"
+
+// writeOFX encodes a Statement as a minimal OFX credit card statement, or bank statement for a
+// deposit account, which Quicken (as QFX) and GnuCash will both import. Amounts follow the same
+// sign convention as Values(): charges are negative and payments are positive.
+func writeOFX(w io.Writer, s *chase.Statement) error {
+	bw := bufio.NewWriter(w)
+	start, end := statementSpan(s)
+	if end.IsZero() {
+		end = time.Now()
+		start = end
+	}
+
+	// A deposit account's statement is a bank statement in its own message set, whose account
+	// also needs the bank's routing number, which statements don't print.
+	msgs, trnrs, rs := "CREDITCARDMSGSRSV1", "CCSTMTTRNRS", "CCSTMTRS"
+	if s.Deposit {
+		msgs, trnrs, rs = "BANKMSGSRSV1", "STMTTRNRS", "STMTRS"
+	}
+	bw.WriteString(ofxHeader)
+	bw.WriteString("<OFX>\n")
+	// Every OFX response starts with a sign-on, which importers insist on even though there was
+	// no server to sign on to.
+	bw.WriteString("<SIGNONMSGSRSV1>\n<SONRS>\n<STATUS>\n<CODE>0\n<SEVERITY>INFO\n</STATUS>\n")
+	fmt.Fprintf(bw, "<DTSERVER>%s\n<LANGUAGE>ENG\n</SONRS>\n</SIGNONMSGSRSV1>\n", time.Now().UTC().Format("20060102150405"))
+	fmt.Fprintf(bw, "<%s>\n<%s>\n<TRNUID>0\n", msgs, trnrs)
+	bw.WriteString("<STATUS>\n<CODE>0\n<SEVERITY>INFO\n</STATUS>\n")
+	acctID := strings.Replace(s.AccountNumber, " ", "", -1)
+	if acctID == "" {
+		acctID = "0"
+	}
+	fmt.Fprintf(bw, "<%s>\n<CURDEF>%s\n", rs, s.Currency)
+	if s.Deposit {
+		fmt.Fprintf(bw, "<BANKACCTFROM>\n<BANKID>000000000\n<ACCTID>%s\n<ACCTTYPE>CHECKING\n</BANKACCTFROM>\n", acctID)
+	} else {
+		fmt.Fprintf(bw, "<CCACCTFROM>\n<ACCTID>%s\n</CCACCTFROM>\n", acctID)
+	}
+	fmt.Fprintf(bw, "<BANKTRANLIST>\n<DTSTART>%s\n<DTEND>%s\n", start.Format(ofxDate), end.Format(ofxDate))
+	for _, t := range s.Transactions {
+		trnType := "DEBIT"
+		if t.Amount < 0 {
+			trnType = "CREDIT"
+		}
+		bw.WriteString("<STMTTRN>\n")
+		fmt.Fprintf(bw, "<TRNTYPE>%s\n", trnType)
+		fmt.Fprintf(bw, "<DTPOSTED>%s\n", t.Posted().Format(ofxDate))
+		if !t.PostDate.IsZero() {
+			fmt.Fprintf(bw, "<DTUSER>%s\n", t.Date.Format(ofxDate))
+		}
+		fmt.Fprintf(bw, "<TRNAMT>%s\n", (-t.Amount).String())
+		fmt.Fprintf(bw, "<FITID>%s\n", t.ID())
+		if t.Reference != "" {
+			fmt.Fprintf(bw, "<REFNUM>%s\n", t.Reference)
+		}
+		fmt.Fprintf(bw, "<NAME>%s\n", ofxEscape(t.Merchant()))
+		if t.OriginalCurrency != "" {
+			fmt.Fprintf(bw, "<MEMO>%s %s\n", formatAmount(t.OriginalAmount), ofxEscape(t.OriginalCurrency))
+		}
+		bw.WriteString("</STMTTRN>\n")
+	}
+	bw.WriteString("</BANKTRANLIST>\n")
+	// OFX reports a credit card's outstanding balance as a negative ledger balance, and a deposit
+	// account's as a positive one. It's the account's balance rather than a total of the
+	// transactions listed, as in a bank's download of a date range, but when filtering may have
+	// dropped the last of them it's only as of the statement's closing date.
+	asOf := end
+	if filtered && !s.PeriodEnd.IsZero() {
+		asOf = s.PeriodEnd
+	}
+	fmt.Fprintf(bw, "<LEDGERBAL>\n<BALAMT>%s\n<DTASOF>%s\n</LEDGERBAL>\n",
+		ledgerBalance(s, s.EndingBalance).String(), asOf.Format(ofxDate))
+	fmt.Fprintf(bw, "</%s>\n</%s>\n</%s>\n", rs, trnrs, msgs)
+	bw.WriteString("</OFX>\n")
+	return bw.Flush()
+}
+
+func ofxEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// errEncrypted is returned by extractText when pdftotext couldn't open a PDF with the password
+// it was given, or without one.
+var errEncrypted = errors.New("the PDF is encrypted and the password is missing or wrong")
+
+// passwords hands out the password for encrypted statements: from -password, then the
+// CHASE_PDF_PASSWORD environment variable, and otherwise by prompting for it on the terminal the
+// first time an encrypted statement turns up. The environment variable and the prompt keep it
+// out of the shell history and the process listing of this command, though pdftotext can only
+// be given it on its own command line.
+var passwords struct {
+	sync.Mutex
+	password string
+	prompted bool
+}
+
+func init() {
+	passwords.password = os.Getenv("CHASE_PDF_PASSWORD")
+}
+
+// pdfPassword returns the password to try first, which may be empty.
+func pdfPassword() string {
+	passwords.Lock()
+	defer passwords.Unlock()
+	if *password != "" {
+		return *password
+	}
+	return passwords.password
+}
+
+// promptPassword asks for the password of file on the terminal, returning "" when there's no
+// terminal to ask on or a password has already been asked for and failed. It's only asked for
+// once, and then used for the rest of the statements too.
+func promptPassword(file, failed string) string {
+	passwords.Lock()
+	defer passwords.Unlock()
+	if passwords.password != failed {
+		// Another statement's prompt got a new password in the meantime.
+		return passwords.password
+	}
+	if passwords.prompted {
+		return ""
+	}
+	passwords.prompted = true
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return ""
+	}
+	defer tty.Close()
+	fmt.Fprintf(tty, "Password for %s: ", file)
+	echo(tty, false)
+	line, _ := bufio.NewReader(tty).ReadString('\n')
+	echo(tty, true)
+	fmt.Fprintln(tty)
+	passwords.password = strings.TrimRight(line, "\r\n")
+	return passwords.password
+}
+
+// echo turns the terminal's echo on or off, as well as stty can.
+func echo(tty *os.File, on bool) {
+	arg := "-echo"
+	if on {
+		arg = "echo"
+	}
+	cmd := exec.Command("stty", arg)
+	cmd.Stdin = tty
+	cmd.Run()
+}
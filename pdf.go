@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// readStatement returns the text of the statement at file: read directly when it's already
+// text (or "-" for stdin), and through pdftotext otherwise. Text that's been gzipped is
+// decompressed.
+func readStatement(file string, isText bool) ([]byte, error) {
+	if file == "-" {
+		body, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, err
+		}
+		return gunzip(body)
+	}
+	if isText {
+		body, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		return gunzip(body)
+	}
+	bin, err := findPdftotext(*pdftotext)
+	if err != nil {
+		return nil, err
+	}
+	modes := []string{*pdfMode}
+	if *pdfMode == "auto" {
+		modes = []string{"raw", "layout"}
+	}
+	var body []byte
+	for i, mode := range modes {
+		pw := pdfPassword()
+		body, err = extractText(bin, file, mode, *timeout, pw)
+		if err == errEncrypted {
+			if retry := promptPassword(file, pw); retry != "" {
+				body, err = extractText(bin, file, mode, *timeout, retry)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to run pdftotext: %v", err)
+		}
+		if i == len(modes)-1 || hasTransactions(body) {
+			verbosef("%s: extracted its text in pdftotext's %s mode\n", file, mode)
+			break
+		}
+		verbosef("%s: no transactions found in pdftotext's %s mode, retrying in %s mode\n", file, mode, modes[i+1])
+	}
+	return body, nil
+}
+
+// pdfModes are the pdftotext arguments for each -pdf-mode; "simple" is its default mode.
+var pdfModes = map[string][]string{
+	"raw":    {"-raw"},
+	"layout": {"-layout"},
+	"simple": nil,
+}
+
+// hasTransactions reports whether anything in body looks like a transaction to one of the
+// layouts it could be in.
+func hasTransactions(body []byte) bool {
+	for _, l := range accountLayouts() {
+		if l.Transaction.Match(body) {
+			return true
+		}
+	}
+	return false
+}
+
+// findPdftotext resolves the pdftotext binary to run: the -pdftotext flag wins, then the
+// CHASE_PDFTOTEXT environment variable, then whatever pdftotext is on the PATH.
+func findPdftotext(override string) (string, error) {
+	if override == "" {
+		override = os.Getenv("CHASE_PDFTOTEXT")
+	}
+	if override != "" {
+		return override, nil
+	}
+	path, err := exec.LookPath("pdftotext")
+	if err != nil {
+		return "", fmt.Errorf("could not find pdftotext on the PATH (it's part of poppler, e.g. `brew install poppler` or `apt install poppler-utils`), use -pdftotext or CHASE_PDFTOTEXT to point at it: %v", err)
+	}
+	return path, nil
+}
+
+// extractText runs pdftotext over file in one of the pdfModes, returning the text of the
+// statement. When pdftotext itself fails (e.g. on a password-protected PDF) its stderr is
+// included in the error. pdftotext is killed if it runs for longer than timeout, unless timeout
+// is zero. A non-empty password is passed on to open encrypted PDFs.
+func extractText(pdftotext, file, mode string, timeout time.Duration, password string) ([]byte, error) {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	args := append(append([]string(nil), pdfModes[mode]...), "-nopgbrk")
+	if password != "" {
+		args = append(args, "-upw", password)
+	}
+	cmd := exec.CommandContext(ctx, pdftotext, append(args, file, "-")...)
+	// Don't wait on output that a child of a killed pdftotext wrapper script is still holding open.
+	cmd.WaitDelay = time.Second
+	body, err := cmd.Output()
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("timed out after %v", timeout)
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok && bytes.Contains(exitErr.Stderr, []byte("Incorrect password")) {
+		return nil, errEncrypted
+	} else if ok && len(exitErr.Stderr) > 0 {
+		return nil, fmt.Errorf("%v: %s", err, bytes.TrimSpace(exitErr.Stderr))
+	} else if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%v (is poppler installed?)", err)
+	}
+	return body, err
+}
+
+// gunzip decompresses body if it starts with the gzip magic number, and returns it unchanged
+// otherwise.
+func gunzip(body []byte) ([]byte, error) {
+	if !bytes.HasPrefix(body, []byte{0x1f, 0x8b}) {
+		return body, nil
+	}
+	r, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// progress keeps a line on stderr saying how far through a batch of statements the conversion
+// is. It stays quiet for a single statement, with -q, and when stderr isn't a terminal, so as
+// not to litter logs.
+type progress struct {
+	total     int
+	completed int
+	line      string
+}
+
+// stderr is where log writes to. Its mutex also guards the progress line, which is cleared
+// before each message is logged and drawn again after it.
+var stderr = &terminal{}
+
+type terminal struct {
+	mu       sync.Mutex
+	progress *progress
+}
+
+func (t *terminal) Write(b []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.progress != nil {
+		fmt.Fprint(os.Stderr, "\r\033[K")
+		defer fmt.Fprint(os.Stderr, t.progress.line)
+	}
+	return os.Stderr.Write(b)
+}
+
+func newProgress(total int) *progress {
+	p := &progress{total: total}
+	if info, err := os.Stderr.Stat(); err == nil && total > 1 && !*quiet && info.Mode()&os.ModeCharDevice != 0 {
+		stderr.mu.Lock()
+		stderr.progress = p
+		stderr.mu.Unlock()
+	}
+	return p
+}
+
+// done records that file has been converted.
+func (p *progress) done(file string) {
+	stderr.mu.Lock()
+	defer stderr.mu.Unlock()
+	p.completed++
+	if stderr.progress == p {
+		p.line = fmt.Sprintf("processing %d/%d: %s", p.completed, p.total, file)
+		fmt.Fprint(os.Stderr, "\r\033[K"+p.line)
+	}
+}
+
+// finish clears the progress line.
+func (p *progress) finish() {
+	stderr.mu.Lock()
+	defer stderr.mu.Unlock()
+	if stderr.progress == p {
+		fmt.Fprint(os.Stderr, "\r\033[K")
+		stderr.progress = nil
+	}
+}
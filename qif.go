@@ -0,0 +1,28 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"github.com/saranrapjs/chase-the-devil/chase"
+)
+
+// writeQIF encodes a Statement as a QIF credit card register, or a bank register for a deposit
+// account, for versions of Quicken that predate OFX import. Amounts are negated the same way
+// Values() does.
+func writeQIF(w io.Writer, s *chase.Statement) error {
+	bw := bufio.NewWriter(w)
+	if s.Deposit {
+		bw.WriteString("!Type:Bank\n")
+	} else {
+		bw.WriteString("!Type:CCard\n")
+	}
+	for _, t := range s.Transactions {
+		fmt.Fprintf(bw, "D%s\n", t.Date.Format("01/02/2006"))
+		fmt.Fprintf(bw, "T%s\n", formatAmount(-t.Amount))
+		fmt.Fprintf(bw, "P%s\n", t.Merchant())
+		bw.WriteString("^\n")
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"strings"
+
+	"github.com/saranrapjs/chase-the-devil/chase"
+)
+
+// redact masks the merchant details of s for -redact, so output can be attached to a bug report.
+// Each merchant becomes a placeholder hashed from its name, so the same merchant is still
+// recognizable throughout, while amounts and dates, and so reconciliation, are left alone. It
+// returns a redacted copy, leaving s as it was.
+func redact(s *chase.Statement) *chase.Statement {
+	r := *s
+	if r.AccountNumber != "" {
+		r.AccountNumber = "XXXX"
+	}
+	r.Transactions = append(chase.Transactions(nil), s.Transactions...)
+	for i := range r.Transactions {
+		redactTransaction(&r.Transactions[i])
+	}
+	return &r
+}
+
+func redactTransaction(t *chase.Transaction) {
+	if t.RawLine != "" {
+		if t.Reference != "" {
+			t.RawLine = strings.Replace(t.RawLine, t.Reference, redacted(t.Reference), 1)
+		}
+		t.RawLine = strings.Replace(t.RawLine, t.MerchantName, redacted(t.MerchantName), 1)
+	}
+	t.MerchantName = redacted(t.MerchantName)
+	if t.NormalizedMerchant != "" {
+		t.NormalizedMerchant = redacted(t.NormalizedMerchant)
+	}
+	if t.Reference != "" {
+		t.Reference = redacted(t.Reference)
+	}
+}
+
+// redacted returns the placeholder for name.
+func redacted(name string) string {
+	sum := sha1.Sum([]byte(name))
+	return "[redacted " + hex.EncodeToString(sum[:4]) + "]"
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/saranrapjs/chase-the-devil/chase"
+)
+
+// jsonReport is how -report json describes one statement: whether it parsed and reconciled,
+// without its transactions.
+type jsonReport struct {
+	Statement       string   `json:"statement"`
+	StartingBalance float64  `json:"startingBalance"`
+	EndingBalance   float64  `json:"endingBalance"`
+	Total           float64  `json:"total"`
+	Reconciled      bool     `json:"reconciled"`
+	Transactions    int      `json:"transactions"`
+	Warnings        []string `json:"warnings"`
+	Error           string   `json:"error,omitempty"`
+}
+
+// writeReport writes a JSON array with a jsonReport for every statement in results.
+func writeReport(w io.Writer, results [][]parsed) error {
+	reports := []jsonReport{}
+	for _, rs := range results {
+		for _, r := range rs {
+			report := jsonReport{Statement: r.name, Warnings: []string{}}
+			if s := r.statement; s != nil {
+				total, ok := s.ReconcileWithin(chase.Cents(*tolerance))
+				report.StartingBalance = s.StartingBalance.Float()
+				report.EndingBalance = s.EndingBalance.Float()
+				report.Total = total.Float()
+				report.Reconciled = ok
+				report.Transactions = len(s.Transactions)
+			}
+			for _, warning := range r.warnings {
+				report.Warnings = append(report.Warnings, warning.Error())
+			}
+			if r.err != nil {
+				report.Error = r.err.Error()
+			}
+			reports = append(reports, report)
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(reports)
+}
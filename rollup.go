@@ -0,0 +1,31 @@
+package main
+
+import (
+	"io"
+
+	"github.com/saranrapjs/chase-the-devil/chase"
+)
+
+// writeRollup writes -rollup's CSV: a row of totals for each month of s, followed by a row of
+// totals for the whole statement. Amounts keep the statement's signs, so sales are positive and
+// payments negative, as in -summary.
+func writeRollup(w io.Writer, s *chase.Statement) error {
+	writer := newCSVWriter(w)
+	if err := writer.Write([]string{"Month", "Sales", "Payments", "Net Change"}); err != nil {
+		return err
+	}
+	for _, m := range s.Monthly() {
+		if err := writer.Write(rollupRow(m.Month.Format("2006-01"), m.Summary)); err != nil {
+			return err
+		}
+	}
+	if err := writer.Write(rollupRow("Total", s.Summary())); err != nil {
+		return err
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func rollupRow(label string, sum chase.Summary) []string {
+	return []string{label, locale.Amount(sum.Sales), locale.Amount(sum.Payments), locale.Amount(sum.Net)}
+}
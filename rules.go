@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/saranrapjs/chase-the-devil/chase"
+)
+
+type ruleSpec struct {
+	Pattern  string `json:"pattern"`
+	Category string `json:"category"`
+}
+
+// loadRules reads merchant categorization rules from path. A .json file holds an array of
+// {"pattern": ..., "category": ...} objects; anything else is read as CSV, with a pattern and a
+// category per row. Either way rules are tried in the order they're listed.
+func loadRules(path string) (chase.Rules, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var raw []ruleSpec
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		if err := json.NewDecoder(f).Decode(&raw); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+	} else {
+		r := csv.NewReader(f)
+		r.FieldsPerRecord = 2
+		records, err := r.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		for _, rec := range records {
+			raw = append(raw, ruleSpec{Pattern: rec[0], Category: rec[1]})
+		}
+	}
+
+	rules := make(chase.Rules, 0, len(raw))
+	for _, r := range raw {
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s: bad pattern for %q: %v", path, r.Category, err)
+		}
+		rules = append(rules, chase.Rule{Pattern: re, Category: r.Category})
+	}
+	return rules, nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/saranrapjs/chase-the-devil/chase"
+)
+
+// sqliteSchema creates the tables -format sqlite fills, unless they're there already. Amounts
+// keep the statement's signs, as in JSON, so charges are positive and payments negative. A
+// statement without a period has empty strings for it rather than NULLs, which never conflict.
+const sqliteSchema = `CREATE TABLE IF NOT EXISTS statements (
+  account TEXT NOT NULL,
+  period_start TEXT,
+  period_end TEXT,
+  currency TEXT,
+  starting_balance NUMERIC,
+  ending_balance NUMERIC,
+  PRIMARY KEY (account, period_start, period_end)
+);
+CREATE TABLE IF NOT EXISTS transactions (
+  id TEXT PRIMARY KEY,
+  account TEXT NOT NULL,
+  date TEXT,
+  post_date TEXT,
+  amount NUMERIC,
+  merchant TEXT,
+  type TEXT,
+  category TEXT,
+  source TEXT
+);
+`
+
+// writeSQLite writes s as SQL that creates the -format sqlite tables and upserts the statement
+// and its transactions into them, or with -db runs it through sqlite3 against that database
+// instead, so that converting a statement again updates its rows rather than duplicating them.
+func writeSQLite(w io.Writer, s *chase.Statement) error {
+	var sql bytes.Buffer
+	sql.WriteString("BEGIN;\n")
+	sql.WriteString(sqliteSchema)
+	fmt.Fprintf(&sql, `INSERT INTO statements VALUES (%s, %s, %s, %s, %s, %s)
+  ON CONFLICT (account, period_start, period_end) DO UPDATE SET currency = excluded.currency,
+  starting_balance = excluded.starting_balance, ending_balance = excluded.ending_balance;
+`, sqlQuote(s.AccountNumber), sqlQuote(jsonDate(s.PeriodStart)), sqlQuote(jsonDate(s.PeriodEnd)), sqlQuote(s.Currency),
+		s.StartingBalance, s.EndingBalance)
+	// Identical transactions on the same day share an ID, so repeats are numbered to keep each
+	// of them; being identical, it doesn't matter which gets which number.
+	seen := map[string]int{}
+	for i := range s.Transactions {
+		t := &s.Transactions[i]
+		id := t.ID()
+		if seen[id]++; seen[id] > 1 {
+			id = fmt.Sprintf("%s-%d", id, seen[id])
+		}
+		fmt.Fprintf(&sql, `INSERT INTO transactions VALUES (%s, %s, %s, %s, %s, %s, %s, %s, %s)
+  ON CONFLICT (id) DO UPDATE SET account = excluded.account, date = excluded.date,
+  post_date = excluded.post_date, amount = excluded.amount, merchant = excluded.merchant,
+  type = excluded.type, category = excluded.category, source = excluded.source;
+`, sqlQuote(id), sqlQuote(s.AccountNumber), sqlDate(t.Date), sqlDate(t.Posted()), t.Amount,
+			sqlQuote(t.MerchantName), sqlQuote(t.Kind.String()), sqlText(t.Category), sqlText(t.Source))
+	}
+	sql.WriteString("COMMIT;\n")
+	if *dbFile == "" {
+		_, err := sql.WriteTo(w)
+		return err
+	}
+	cmd := exec.Command(*sqlite3, "-bail", *dbFile)
+	cmd.Stdin = &sql
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if _, ok := err.(*exec.Error); ok {
+			return fmt.Errorf("could not run sqlite3, use -sqlite3 to point at it: %v", err)
+		}
+		return fmt.Errorf("sqlite3 failed on %s: %v: %s", *dbFile, err, bytes.TrimSpace(out))
+	}
+	return nil
+}
+
+// sqlQuote returns s as an SQL string literal.
+func sqlQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// sqlText is sqlQuote, with NULL for an empty s.
+func sqlText(s string) string {
+	if s == "" {
+		return "NULL"
+	}
+	return sqlQuote(s)
+}
+
+// sqlDate returns d as an SQL date literal, or NULL when it's unset.
+func sqlDate(d time.Time) string {
+	if d.IsZero() {
+		return "NULL"
+	}
+	return sqlQuote(d.Format("2006-01-02"))
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"io"
+	"path/filepath"
+
+	"github.com/saranrapjs/chase-the-devil/chase"
+)
+
+// streamCSV converts the statement at file to CSV on w for -stream, writing each transaction as
+// soon as it's parsed instead of collecting and sorting them first. The transactions come out in
+// the order they're printed on the statement, and reconciliation only happens once they've all
+// been written, so a statement that fails to reconcile has still been converted.
+func streamCSV(w io.Writer, file string, rules chase.Rules, filters []filter) error {
+	body, err := readStatement(file, *textInput)
+	if err != nil {
+		return ioError{err}
+	}
+	body = toUTF8(body, *encoding)
+	sources = []string{file}
+	if err := writeComments(w); err != nil {
+		return ioError{err}
+	}
+	writer := newCSVWriter(w)
+	writer.Write(csvHeaders(&chase.Statement{}))
+	// The whole statement has been read already, so -profile auto can still try each profile
+	// on it before the transactions start coming out.
+	parser := newParser(file)
+	if autoProfile {
+		parser.Layout = detectLayout(file, file, body)
+	}
+	var written int
+	statement, err := parser.Stream(body, func(t chase.Transaction) error {
+		t.Source = filepath.Base(file)
+		if *normalize {
+			t.NormalizedMerchant = chase.NormalizeMerchant(t.MerchantName)
+		}
+		if rules != nil {
+			t.Category = rules.Category(&t)
+		}
+		for _, keep := range filters {
+			if !keep(&t) {
+				return nil
+			}
+		}
+		if *redactOutput {
+			redactTransaction(&t)
+		}
+		written++
+		writer.Write(csvValues(&t))
+		return writer.Error()
+	})
+	// The streamed Statement has no transactions to reconcile again, so the balance row goes
+	// by whether Stream could.
+	if eerr := checkEnding(statement); eerr != nil {
+		writer.Flush()
+		return eerr
+	}
+	balanced := reconciled(err)
+	statement, _, err = checkParse(file, body, statement, err)
+	if err == nil && *balanceRow && balanced {
+		writer.Write(csvBalanceRow(statement))
+	}
+	writer.Flush()
+	if werr := writer.Error(); werr != nil {
+		return ioError{werr}
+	}
+	verbosef("Wrote %d transactions from %s\n", written, file)
+	return err
+}
+
+// reconciled reports whether err, as returned by a Parser, leaves the statement reconciled.
+func reconciled(err error) bool {
+	if errs, ok := err.(chase.Errors); ok {
+		for _, e := range errs {
+			if !reconciled(e) {
+				return false
+			}
+		}
+		return true
+	}
+	_, ok := err.(*chase.ReconcileError)
+	return !ok
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	// Imported under another name, as -debug's flag is named debug.
+	buildinfo "runtime/debug"
+)
+
+// version, commit and date describe the build, and are set when packaging with e.g.
+//
+//	go build -ldflags "-X main.version=1.2.0 -X main.commit=$(git rev-parse HEAD) -X main.date=$(date -u +%Y-%m-%d)"
+//
+// Otherwise the commit and date are taken from the VCS information go embeds, if there is any.
+var (
+	version = "dev"
+	commit  = ""
+	date    = ""
+)
+
+// versionString is what -version prints.
+func versionString() string {
+	commit, date := commit, date
+	if info, ok := buildinfo.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			switch {
+			case s.Key == "vcs.revision" && commit == "":
+				commit = s.Value
+			case s.Key == "vcs.time" && date == "":
+				date = s.Value
+			}
+		}
+	}
+	if commit == "" {
+		commit = "unknown"
+	}
+	if date == "" {
+		date = "unknown"
+	}
+	return fmt.Sprintf("chase-the-devil %s (commit %s, built %s)", version, commit, date)
+}
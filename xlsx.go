@@ -0,0 +1,162 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/saranrapjs/chase-the-devil/chase"
+)
+
+// The fixed parts of a minimal Office Open XML workbook with a single sheet. styles.xml defines
+// the cell formats the sheet refers to by index: 0 is the default, 1 a date and 2 an amount.
+var xlsxParts = []struct{ name, body string }{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>
+</Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`},
+	{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Transactions" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>
+</Relationships>`},
+	{"xl/styles.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<numFmts count="1"><numFmt numFmtId="164" formatCode="#,##0.00"/></numFmts>
+<fonts count="1"><font><sz val="11"/><name val="Calibri"/></font></fonts>
+<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>
+<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>
+<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>
+<cellXfs count="3">
+<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>
+<xf numFmtId="14" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>
+<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>
+</cellXfs>
+</styleSheet>`},
+}
+
+// xlsxCell is a spreadsheet cell: text, a date, or an amount.
+type xlsxCell struct {
+	text   string
+	date   time.Time
+	amount chase.Cents
+	kind   int
+}
+
+const (
+	xlsxText = iota
+	xlsxDate
+	xlsxAmount
+)
+
+// xlsxEpoch is day zero of Excel's date serial numbers.
+var xlsxEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// writeXLSX writes a Statement as an Excel workbook with the same columns as CSV output, but with
+// real dates and numeric amounts, so spreadsheets don't have to guess at them.
+func writeXLSX(w io.Writer, s *chase.Statement) error {
+	var sheet bytes.Buffer
+	sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	sheet.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	var headers []xlsxCell
+	for _, h := range csvHeaders(s) {
+		headers = append(headers, xlsxCell{text: h})
+	}
+	writeXLSXRow(&sheet, 1, headers)
+	for i := range s.Transactions {
+		writeXLSXRow(&sheet, i+2, xlsxValues(&s.Transactions[i]))
+	}
+	sheet.WriteString(`</sheetData></worksheet>`)
+
+	zw := zip.NewWriter(w)
+	for _, part := range xlsxParts {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, part.body); err != nil {
+			return err
+		}
+	}
+	f, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(sheet.Bytes()); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// xlsxValues is the spreadsheet version of csvValues.
+func xlsxValues(t *chase.Transaction) []xlsxCell {
+	values := t.Values()
+	row := []xlsxCell{
+		{text: values[0]},
+		{date: t.Date, kind: xlsxDate},
+		{date: t.Posted(), kind: xlsxDate},
+		{text: values[3]},
+		{amount: -t.Amount, kind: xlsxAmount},
+		{text: t.Category},
+		{text: t.Source},
+	}
+	if columns != nil {
+		picked := make([]xlsxCell, len(columns))
+		for i, col := range columns {
+			picked[i] = row[col]
+		}
+		return picked
+	}
+	cells := row[:5:5]
+	if *rulesFile != "" {
+		cells = append(cells, row[5])
+	}
+	if *withSource {
+		cells = append(cells, row[6])
+	}
+	return cells
+}
+
+func writeXLSXRow(b *bytes.Buffer, n int, cells []xlsxCell) {
+	fmt.Fprintf(b, `<row r="%d">`, n)
+	for i, c := range cells {
+		ref := fmt.Sprintf("%s%d", xlsxColumn(i), n)
+		switch c.kind {
+		case xlsxDate:
+			y, m, d := c.date.Date()
+			days := time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Sub(xlsxEpoch).Hours() / 24
+			fmt.Fprintf(b, `<c r="%s" s="1"><v>%.0f</v></c>`, ref, days)
+		case xlsxAmount:
+			fmt.Fprintf(b, `<c r="%s" s="2"><v>%s</v></c>`, ref, c.amount)
+		default:
+			fmt.Fprintf(b, `<c r="%s" t="inlineStr"><is><t>`, ref)
+			xml.EscapeText(b, []byte(c.text))
+			b.WriteString(`</t></is></c>`)
+		}
+	}
+	b.WriteString(`</row>`)
+}
+
+// xlsxColumn returns the letters naming the i'th column, counting from 0: A to Z, then AA.
+func xlsxColumn(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}